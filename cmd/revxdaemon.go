@@ -6,6 +6,7 @@ import (
 	"github.com/revx-official/output/log"
 	"github.com/revx-official/revx/pkg/boot"
 	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
 )
 
 // Description:
@@ -13,7 +14,7 @@ import (
 //	Initializes this package.
 //	Keep logging moderate for release builds.
 func init() {
-	log.Level = log.LevelInfo
+	logging.SetDefaultLevel(log.LevelInfo)
 }
 
 // Specifies whether to enable verbose logging.
@@ -32,7 +33,8 @@ func main() {
 	flag.Parse()
 
 	if flagVerbose {
-		log.Level = log.LevelTrace
+		logging.Verbose = true
+		logging.SetDefaultLevel(log.LevelTrace)
 	}
 
 	boot.Boot(flagConfigFilePath)
@@ -0,0 +1,206 @@
+package accesslog
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/ipfilter"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The accesslog subsystem logger.
+var log = logging.NewLogger("accesslog")
+
+// Description:
+//
+//	Represents a single access log entry.
+//	Every field can be used in access log templates.
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`      // The time the request was received.
+	ClientIp  string        `json:"clientIp"`  // The client ip address.
+	User      string        `json:"user"`      // The authenticated user, if any.
+	Method    string        `json:"method"`    // The http method.
+	Host      string        `json:"host"`      // The requested host.
+	Path      string        `json:"path"`      // The requested path.
+	Query     string        `json:"query"`     // The raw query string.
+	Uri       string        `json:"uri"`       // The request uri, i.e. the path and query.
+	Protocol  string        `json:"protocol"`  // The http protocol version.
+	Status    int           `json:"status"`    // The response status code.
+	Bytes     int64         `json:"bytes"`     // The amount of response body bytes.
+	Duration  time.Duration `json:"-"`         // The time it took to serve the request.
+	Server    string        `json:"server"`    // The name of the server handling the request.
+	Upstream  string        `json:"upstream"`  // The upstream chosen by the load balancer.
+	RequestId string        `json:"requestId"` // The request id.
+	UserAgent string        `json:"userAgent"` // The client user agent.
+	Referer   string        `json:"referer"`   // The referer.
+}
+
+// Description:
+//
+//	Writes access log entries in a configured format to a configured output.
+type AccessLogger struct {
+	mutex     sync.Mutex
+	writer    io.Writer
+	formatter AccessLogFormatter
+}
+
+// The global access logger.
+// Is nil, if access logging is disabled.
+var Global *AccessLogger
+
+// Description:
+//
+//	Creates a new access logger from the given configuration.
+//
+// Parameters:
+//
+//	conf The access log configuration.
+//
+// Returns:
+//
+//	The created access logger, or an error.
+func NewAccessLogger(conf config.ConfigAccessLog) (*AccessLogger, error) {
+	formatter, err := NewAccessLogFormatter(conf.Format, conf.Template)
+
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := logging.OpenOutput(conf.Output, conf.Rotation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessLogger{writer: writer, formatter: formatter}, nil
+}
+
+// Description:
+//
+//	Initializes the global access logger using the given configuration.
+//
+// Parameters:
+//
+//	conf The access log configuration.
+//
+// Returns:
+//
+//	An error, if the access logger cannot be created.
+func InitAccessLog(conf config.ConfigAccessLog) error {
+	if !conf.Enabled {
+		Global = nil
+		return nil
+	}
+
+	logger, err := NewAccessLogger(conf)
+
+	if err != nil {
+		return err
+	}
+
+	Global = logger
+	return nil
+}
+
+// Description:
+//
+//	Writes an access log entry.
+//
+// Parameters:
+//
+//	entry The entry to write.
+func (logger *AccessLogger) Write(entry *AccessLogEntry) {
+	line, err := logger.formatter(entry)
+
+	if err != nil {
+		log.Errorf("accesslog: unable to format entry: %s", err)
+		return
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	_, err = logger.writer.Write(append(line, '\n'))
+
+	if err != nil {
+		log.Errorf("accesslog: unable to write entry: %s", err)
+	}
+}
+
+// Description:
+//
+//	Middleware writing an access log entry after the wrapped handler completed.
+//	If access logging is disabled, the handler is returned as is.
+//
+// Parameters:
+//
+//	handler The handler to wrap.
+//
+// Returns:
+//
+//	The wrapping handler.
+func Middleware(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+	logger := Global
+
+	if logger == nil {
+		return handler
+	}
+
+	return func(request *http.Request, response http.ResponseWriter) {
+		start := time.Now()
+
+		request, info := proxy.WithRequestInfo(request)
+		recorder := router.NewResponseRecorder(response)
+
+		handler(request, recorder)
+
+		entry := NewAccessLogEntry(request, info, recorder, start)
+		logger.Write(entry)
+	}
+}
+
+// Description:
+//
+//	Creates a new access log entry for a completed request.
+//
+// Parameters:
+//
+//	request 	The request.
+//	info 		The proxy request information.
+//	recorder 	The response recorder.
+//	start 		The time the request was received.
+//
+// Returns:
+//
+//	The access log entry.
+func NewAccessLogEntry(request *http.Request, info *proxy.ProxyRequestInfo, recorder *router.ResponseRecorder, start time.Time) *AccessLogEntry {
+	entry := AccessLogEntry{
+		Time:      start,
+		ClientIp:  ipfilter.RequestClientIp(request),
+		Method:    request.Method,
+		Host:      request.Host,
+		Path:      request.URL.Path,
+		Query:     request.URL.RawQuery,
+		Uri:       request.URL.RequestURI(),
+		Protocol:  request.Proto,
+		Status:    recorder.Status(),
+		Bytes:     recorder.Size,
+		Duration:  time.Since(start),
+		Server:    info.Server,
+		RequestId: info.RequestId,
+		User:      info.User,
+		UserAgent: request.UserAgent(),
+		Referer:   request.Referer(),
+	}
+
+	if info.Upstream != nil {
+		entry.Upstream = info.Upstream.TargetUrl.String()
+	}
+
+	return &entry
+}
@@ -0,0 +1,200 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Constant declarations.
+const (
+	// The json access log format.
+	FormatJson string = "json"
+
+	// The Apache combined log format.
+	FormatCombined string = "combined"
+
+	// The custom template access log format.
+	FormatTemplate string = "template"
+)
+
+// Description:
+//
+//	Function definition for access log formatters.
+//	A formatter converts an entry to a single log line, without a trailing newline.
+type AccessLogFormatter = func(entry *AccessLogEntry) ([]byte, error)
+
+// Description:
+//
+//	The json representation of an access log entry.
+//	Extends the entry with the duration in milliseconds.
+type accessLogJsonEntry struct {
+	*AccessLogEntry
+	Duration float64 `json:"duration"`
+}
+
+// Description:
+//
+//	Creates an access log formatter for the given format.
+//
+// Parameters:
+//
+//	format 	The access log format.
+//	text 	The template text, used by the template format.
+//
+// Returns:
+//
+//	The access log formatter, or an error.
+func NewAccessLogFormatter(format string, text string) (AccessLogFormatter, error) {
+	switch format {
+	case "", FormatCombined:
+		return formatCombined, nil
+	case FormatJson:
+		return formatJson, nil
+	case FormatTemplate:
+		return newTemplateFormatter(text)
+	}
+
+	return nil, fmt.Errorf("accesslog: unknown format: %s", format)
+}
+
+// Description:
+//
+//	Formats an entry as json.
+//
+// Parameters:
+//
+//	entry The entry to format.
+//
+// Returns:
+//
+//	The formatted entry, or an error.
+func formatJson(entry *AccessLogEntry) ([]byte, error) {
+	return json.Marshal(accessLogJsonEntry{
+		AccessLogEntry: entry,
+		Duration:       float64(entry.Duration) / float64(time.Millisecond),
+	})
+}
+
+// Description:
+//
+//	Formats an entry using the Apache combined log format.
+//
+// Parameters:
+//
+//	entry The entry to format.
+//
+// Returns:
+//
+//	The formatted entry, or an error.
+func formatCombined(entry *AccessLogEntry) ([]byte, error) {
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"",
+		entry.ClientIp,
+		escape(orDash(entry.User)),
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		escape(entry.Method),
+		escape(entry.Uri),
+		escape(entry.Protocol),
+		entry.Status,
+		bytesOrDash(entry.Bytes),
+		escape(orDash(entry.Referer)),
+		escape(orDash(entry.UserAgent)),
+	)
+
+	return []byte(line), nil
+}
+
+// Description:
+//
+//	Creates a formatter using a custom Go text template.
+//
+// Parameters:
+//
+//	text The template text.
+//
+// Returns:
+//
+//	The template formatter, or an error, if the template cannot be parsed.
+func newTemplateFormatter(text string) (AccessLogFormatter, error) {
+	if text == "" {
+		return nil, fmt.Errorf("accesslog: template format requires a template")
+	}
+
+	tmpl, err := template.New("accesslog").Parse(text)
+
+	if err != nil {
+		return nil, fmt.Errorf("accesslog: unable to parse template: %s", err)
+	}
+
+	return func(entry *AccessLogEntry) ([]byte, error) {
+		// Client supplied values are escaped, so they cannot break or forge log lines.
+		escaped := *entry
+		escaped.User = escape(entry.User)
+		escaped.Method = escape(entry.Method)
+		escaped.Host = escape(entry.Host)
+		escaped.Path = escape(entry.Path)
+		escaped.Query = escape(entry.Query)
+		escaped.Uri = escape(entry.Uri)
+		escaped.Protocol = escape(entry.Protocol)
+		escaped.UserAgent = escape(entry.UserAgent)
+		escaped.Referer = escape(entry.Referer)
+
+		buffer := bytes.Buffer{}
+		err := tmpl.Execute(&buffer, &escaped)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return buffer.Bytes(), nil
+	}, nil
+}
+
+// Description:
+//
+//	Escapes a client supplied value the way Apache does, so it cannot break or forge log lines.
+//	Quotes and backslashes are prefixed with a backslash, control and non-ascii bytes are written as \xhh.
+func escape(value string) string {
+	builder := strings.Builder{}
+
+	for index := 0; index < len(value); index++ {
+		char := value[index]
+
+		switch {
+		case char == '"' || char == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(char)
+		case char < 0x20 || char >= 0x7f:
+			fmt.Fprintf(&builder, "\\x%02x", char)
+		default:
+			builder.WriteByte(char)
+		}
+	}
+
+	return builder.String()
+}
+
+// Description:
+//
+//	Replaces empty values with a dash.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// Description:
+//
+//	Formats a byte count, replacing zero with a dash.
+func bytesOrDash(count int64) string {
+	if count == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d", count)
+}
@@ -1,14 +1,38 @@
 package api
 
 import (
-	"github.com/revx-official/output/log"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/revx-official/revx/pkg/auth"
 	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/health"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/proxy"
 	"github.com/revx-official/revx/pkg/router"
+	"github.com/revx-official/revx/pkg/socket"
+	"github.com/revx-official/revx/pkg/stream"
+	"github.com/revx-official/revx/pkg/systemd"
+	"github.com/revx-official/revx/pkg/tracing"
 )
 
+// The api subsystem logger.
+var log = logging.NewLogger("api")
+
 // The global router engine.
 var Router router.Router
 
+// Specifies whether revx is shutting down.
+// While draining, the readiness endpoint fails.
+var Draining atomic.Bool
+
 // Description:
 //
 //	Initializes the global router engine.
@@ -19,14 +43,187 @@ func InitApi() {
 // Description:
 //
 //	Runs the global router engine, i.e. provides the api endpoints.
+//	Blocks until the server fails or revx has been shut down gracefully on SIGINT or SIGTERM.
+//	On SIGUSR2, a new revx process is started, taking over the listener, and this process shuts down.
+//	Readiness, reloads and shutdowns are reported to systemd, if revx runs as a notify service.
 func Boot() {
 	log.Infof("api: running server ...")
 	log.Infof("api: serving on port: %d", config.Global.Port)
 
-	err := Router.Run(config.Global.Port)
+	listener, err := socket.Listen(config.Global.Port)
+
+	if err != nil {
+		log.Fatalf("api: unable to listen: %s", err)
+	}
+
+	socket.CloseUnusedListeners()
+
+	options, err := ServerOptions(config.Global)
+
+	if err != nil {
+		log.Fatalf("api: %s", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	upgrades := make(chan os.Signal, 1)
+	socket.NotifyUpgrade(upgrades)
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- Router.Serve(listener, options)
+	}()
+
+	socket.NotifyUpgradeReady()
+	systemd.NotifyReady()
+	systemd.RunWatchdog()
+
+	for {
+		select {
+		case err := <-errs:
+			if err != nil {
+				log.Fatalf("api: unable to run server: %s", err)
+			}
+
+			return
+		case sig := <-signals:
+			log.Infof("api: received signal: %s", sig)
+			systemd.NotifyStopping()
+			Shutdown(config.Global.Shutdown)
+
+			return
+		case <-upgrades:
+			log.Infof("api: upgrading ...")
+			systemd.NotifyReloading()
+
+			timeout := time.Duration(config.Global.Upgrade.ReadyTimeout) * time.Millisecond
+			err := socket.Upgrade(timeout)
+
+			if err != nil {
+				log.Errorf("api: upgrade failed: %s", err)
+				systemd.NotifyReady()
+				continue
+			}
+
+			// The new process already serves and reported itself as the main process to systemd,
+			// hence there is no need to delay closing the listener or to report stopping.
+			conf := config.Global.Shutdown
+			conf.Delay = 0
+
+			Shutdown(conf)
+			return
+		}
+	}
+}
+
+// Description:
+//
+//	Creates the router server options from the given configuration.
+//	Loads the listener certificate, if TLS is enabled.
+//	Client certificates are requested, if any server requires them. They are verified per server,
+//	so servers without client certificate authentication stay open.
+//
+// Parameters:
+//
+//	conf The configuration.
+//
+// Returns:
+//
+//	The router server options, or an error, if a certificate cannot be loaded.
+func ServerOptions(conf *config.ConfigRevx) (router.RouterServerOptions, error) {
+	options := router.RouterServerOptions{
+		Http2: conf.Http2.Enabled,
+		H2c:   conf.Http2.H2c,
+	}
+
+	if !conf.Tls.Enabled {
+		return options, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(conf.Tls.CertFile, conf.Tls.KeyFile)
+
+	if err != nil {
+		return options, fmt.Errorf("unable to load certificate: %s", err)
+	}
+
+	options.TlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	for _, server := range conf.Servers {
+		if !server.ClientAuth.Enabled {
+			continue
+		}
+
+		if options.TlsConfig.ClientCAs == nil {
+			options.TlsConfig.ClientCAs = x509.NewCertPool()
+			options.TlsConfig.ClientAuth = tls.RequestClientCert
+		}
+
+		err = auth.AppendCertsFromFile(options.TlsConfig.ClientCAs, server.ClientAuth.CaFile)
+
+		if err != nil {
+			return options, err
+		}
+	}
+
+	return options, nil
+}
+
+// Description:
+//
+//	Gracefully shuts down revx.
+//	Fails readiness, stops accepting connections, drains in-flight requests and stream connections,
+//	stops all health check routines and flushes pending spans.
+//
+// Parameters:
+//
+//	conf The shutdown configuration.
+func Shutdown(conf config.ConfigShutdown) {
+	Draining.Store(true)
+	log.Infof("api: shutting down ...")
+
+	if conf.Delay > 0 {
+		log.Infof("api: waiting %d ms before closing the listener ...", conf.Delay)
+		time.Sleep(time.Duration(conf.Delay) * time.Millisecond)
+	}
+
+	timeout := time.Duration(conf.DrainTimeout) * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	log.Infof("api: draining in-flight requests ...")
+	proxy.CloseWebSockets()
+
+	// The http and stream listeners are closed at the same time, then both are drained.
+	streamErr := make(chan error, 1)
+
+	go func() {
+		streamErr <- stream.Shutdown(ctx)
+	}()
+
+	err := Router.Shutdown(ctx)
+
+	if err != nil {
+		log.Warnf("api: drain timeout exceeded, remaining connections closed: %s", err)
+	}
+
+	err = <-streamErr
+
+	if err != nil {
+		log.Warnf("api: drain timeout exceeded, remaining stream connections closed: %s", err)
+	}
+
+	health.StopHealthCheckRoutines()
+
+	err = tracing.Shutdown(ctx)
 
 	if err != nil {
-		log.Fatalf("api: unable to run server: %s", err)
+		log.Warnf("api: unable to flush pending spans: %s", err)
 	}
 
+	log.Infof("api: shutdown complete")
 }
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// Description:
+//
+//	Represents the log levels returned by the log api.
+type LogLevelsResponse struct {
+	Default    string            `json:"default"`    // The default log level.
+	Subsystems map[string]string `json:"subsystems"` // The log levels by subsystem.
+}
+
+// Description:
+//
+//	Represents a log level change request.
+type LogLevelRequest struct {
+	Level string `json:"level"` // The new log level, e.g. debug.
+}
+
+// Description:
+//
+//	Endpoint: GET /log
+//
+// Parameters:
+//
+//	request The router request.
+func HandleLogLevels(request *router.Request) *router.Response {
+	log.Infof("%s: %s", "api: request", request.Path)
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       currentLogLevels(),
+	}
+}
+
+// Description:
+//
+//	Endpoint: PUT /log
+//	Changes the default log level.
+//
+// Parameters:
+//
+//	request The router request.
+func HandleSetDefaultLogLevel(request *router.Request) *router.Response {
+	log.Infof("%s: %s", "api: request", request.Path)
+
+	level, response := parseLogLevelRequest(request)
+
+	if response != nil {
+		return response
+	}
+
+	logging.SetDefaultLevel(level)
+	log.Infof("api: default log level changed: %s", logging.LevelName(level))
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       currentLogLevels(),
+	}
+}
+
+// Description:
+//
+//	Endpoint: PUT /log/:subsystem
+//	Changes the log level of a single subsystem.
+//
+// Parameters:
+//
+//	request The router request.
+func HandleSetSubsystemLogLevel(request *router.Request) *router.Response {
+	log.Infof("%s: %s", "api: request", request.Path)
+
+	level, response := parseLogLevelRequest(request)
+
+	if response != nil {
+		return response
+	}
+
+	name := request.PathParameters["subsystem"]
+	err := logging.SetLevel(name, level)
+
+	if err != nil {
+		return &router.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       InfoErrorResponse{Message: "Subsystem not found."},
+		}
+	}
+
+	log.Infof("api: log level changed: %s: %s", name, logging.LevelName(level))
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       currentLogLevels(),
+	}
+}
+
+// Description:
+//
+//	Endpoint: DELETE /log/:subsystem
+//	Resets the log level of a single subsystem to the default log level.
+//
+// Parameters:
+//
+//	request The router request.
+func HandleResetSubsystemLogLevel(request *router.Request) *router.Response {
+	log.Infof("%s: %s", "api: request", request.Path)
+
+	name := request.PathParameters["subsystem"]
+	err := logging.ResetLevel(name)
+
+	if err != nil {
+		return &router.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       InfoErrorResponse{Message: "Subsystem not found."},
+		}
+	}
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       currentLogLevels(),
+	}
+}
+
+// Description:
+//
+//	Parses the log level of a log level change request.
+//
+// Parameters:
+//
+//	request The router request.
+//
+// Returns:
+//
+//	The log level, or an error response, if the request is invalid.
+func parseLogLevelRequest(request *router.Request) (uint32, *router.Response) {
+	body := LogLevelRequest{}
+	err := json.Unmarshal([]byte(request.Body), &body)
+
+	if err != nil {
+		return 0, &router.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       InfoErrorResponse{Message: "Invalid request body."},
+		}
+	}
+
+	level, err := logging.ParseLevel(body.Level)
+
+	if err != nil {
+		return 0, &router.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       InfoErrorResponse{Message: "Invalid log level."},
+		}
+	}
+
+	return level, nil
+}
+
+// Description:
+//
+//	Gets the current log levels.
+//
+// Returns:
+//
+//	The current log levels.
+func currentLogLevels() LogLevelsResponse {
+	return LogLevelsResponse{
+		Default:    logging.LevelName(logging.DefaultLevel()),
+		Subsystems: logging.Levels(),
+	}
+}
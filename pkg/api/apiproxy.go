@@ -1,24 +1,97 @@
 package api
 
 import (
-	"github.com/revx-official/output/log"
+	"net/http"
+	"strings"
 
+	"github.com/revx-official/revx/pkg/accesslog"
+	"github.com/revx-official/revx/pkg/auth"
 	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/cors"
 	"github.com/revx-official/revx/pkg/health"
+	"github.com/revx-official/revx/pkg/ipfilter"
 	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+	"github.com/revx-official/revx/pkg/security"
+	"github.com/revx-official/revx/pkg/tracing"
+	"github.com/revx-official/revx/pkg/waf"
 )
 
 // Description:
 //
-//	Creates an endpoint for a route corresponding to the given http method.
+//	Creates the handler for a reverse proxy.
+//	The load balancing handler is wrapped by all middlewares, the first middleware being the innermost.
+//	Authentication runs innermost, preceded by the web application firewall, CORS handling and the ip filter,
+//	so rejected requests are still traced and logged, and still carry the security headers.
 //
 // Parameters:
 //
-//	prox 	The reverse proxy.
-//	method 	The http method to handle.
-func CreateEndpointProxyHandler(prox *proxy.ReverseProxyServerInfo, method string) {
+//	prox The reverse proxy.
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The handler, or an error, if a middleware cannot be created.
+func CreateProxyHandler(prox *proxy.ReverseProxyServerInfo, conf config.ConfigReverseProxyServer) (router.RouterProxyHandlerFunc, error) {
+	middlewares, err := auth.Middlewares(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	firewall, err := waf.Middleware(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	crossOrigin, err := cors.Middleware(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := ipfilter.Middleware(config.Global.IpFilter, conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	securityHeaders, err := security.HeadersMiddleware(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	middlewares = append(middlewares,
+		firewall,
+		crossOrigin,
+		filter,
+		securityHeaders,
+		tracing.Middleware(conf, ipfilter.RequestClientIp),
+		accesslog.Middleware,
+		proxy.RequestIdMiddleware(config.Global.RequestId),
+	)
+
 	handler := proxy.LoadBalancingHandler(prox)
 
+	for _, middleware := range middlewares {
+		handler = middleware(handler)
+	}
+
+	return handler, nil
+}
+
+// Description:
+//
+//	Creates an endpoint for a route corresponding to the given http method.
+//
+// Parameters:
+//
+//	prox 		The reverse proxy.
+//	method 		The http method to handle.
+//	handler 	The proxy handler.
+func CreateEndpointProxyHandler(prox *proxy.ReverseProxyServerInfo, method string, handler router.RouterProxyHandlerFunc) {
 	Router.ProxyHandle(method, prox.Context, handler)
 	Router.ProxyHandle(method, prox.Context+"/*path", handler)
 }
@@ -30,13 +103,34 @@ func CreateEndpointProxyHandler(prox *proxy.ReverseProxyServerInfo, method strin
 // Parameters:
 //
 //	prox The reverse proxy.
-func CreateEndpointsForProxy(prox *proxy.ReverseProxyServerInfo) {
+//	conf The server configuration.
+//
+// Returns:
+//
+//	An error, if the proxy handler cannot be created.
+func CreateEndpointsForProxy(prox *proxy.ReverseProxyServerInfo, conf config.ConfigReverseProxyServer) error {
+	handler, err := CreateProxyHandler(prox, conf)
+
+	if err != nil {
+		return err
+	}
+
+	options := false
+
 	for _, method := range prox.AllowedMethods {
-		CreateEndpointProxyHandler(prox, method)
+		CreateEndpointProxyHandler(prox, method, handler)
+		options = options || strings.EqualFold(method, http.MethodOptions)
+	}
+
+	// Preflight requests are answered by revx, even if the upstreams do not accept OPTIONS requests.
+	if conf.Cors.Enabled && !options {
+		CreateEndpointProxyHandler(prox, http.MethodOptions, handler)
 	}
 
 	healthCheck := health.NewHealthCheckRoutine(prox)
 	health.RunHealthCheckRoutine(healthCheck)
+
+	return nil
 }
 
 // Description:
@@ -47,10 +141,14 @@ func CreateReverseProxies() {
 		prox, err := proxy.NewReverseProxyServer(server)
 
 		if err != nil {
-			log.Fatalf("api: unable to create reverse proxy: %s", prox.Name)
+			log.Fatalf("api: unable to create reverse proxy: %s: %s", server.Name, err)
 		}
 
-		CreateEndpointsForProxy(prox)
+		err = CreateEndpointsForProxy(prox, server)
+
+		if err != nil {
+			log.Fatalf("api: unable to create endpoints: %s: %s", server.Name, err)
+		}
 	}
 }
 
@@ -3,11 +3,13 @@ package api
 import (
 	"net/http"
 
-	"github.com/revx-official/output/log"
+	"github.com/revx-official/revx/pkg/auth"
 	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/ipfilter"
 	"github.com/revx-official/revx/pkg/proxy"
 	"github.com/revx-official/revx/pkg/revx"
 	"github.com/revx-official/revx/pkg/router"
+	"github.com/revx-official/revx/pkg/waf"
 )
 
 // Description:
@@ -49,6 +51,37 @@ func HandleConfig(request *router.Request) *router.Response {
 	}
 }
 
+// Description:
+//
+//	Represents the response of the readiness api.
+type ReadyResponse struct {
+	Ready bool `json:"ready"` // Whether revx accepts new requests.
+}
+
+// Description:
+//
+//	Endpoint: /ready
+//	Fails with 503, while revx is shutting down.
+//
+// Parameters:
+//
+//	context The http context.
+func HandleReady(request *router.Request) *router.Response {
+	log.Tracef("%s: %s", "api: request", request.Path)
+
+	if Draining.Load() {
+		return &router.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ReadyResponse{Ready: false},
+		}
+	}
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       ReadyResponse{Ready: true},
+	}
+}
+
 // Description:
 //
 //	Endpoint: /inspect
@@ -91,14 +124,104 @@ func HandleInspectByName(request *router.Request) *router.Response {
 	}
 }
 
+// Description:
+//
+//	Endpoint: /apikeys
+//	Returns the usage of all api keys.
+//
+// Parameters:
+//
+//	context The http context.
+func HandleApiKeys(request *router.Request) *router.Response {
+	log.Infof("%s: %s", "api: request", request.Path)
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       auth.ApiKeyUsage(),
+	}
+}
+
+// Description:
+//
+//	Endpoint: /waf
+//	Returns the rule hit statistics of all web application firewalls.
+//
+// Parameters:
+//
+//	context The http context.
+func HandleWaf(request *router.Request) *router.Response {
+	log.Infof("%s: %s", "api: request", request.Path)
+
+	return &router.Response{
+		StatusCode: http.StatusOK,
+		Body:       waf.Hits(),
+	}
+}
+
+// Description:
+//
+//	Restricts an api handler to the clients passing the admin filter.
+//
+// Parameters:
+//
+//	filter 	The admin filter.
+//	handler The api handler.
+//
+// Returns:
+//
+//	The restricted handler.
+func restrictHandler(filter *ipfilter.Filter, handler router.RouterHandlerFunc) router.RouterHandlerFunc {
+	return func(request *router.Request) *router.Response {
+		ip, allowed := filter.Check(request.RemoteAddr, request.Headers["X-Forwarded-For"])
+
+		if !allowed {
+			log.Warnf("api: rejected client: %s: %s", ip, request.Path)
+
+			return &router.Response{
+				StatusCode: filter.Status(),
+				Body:       InfoErrorResponse{Message: http.StatusText(filter.Status()) + "."},
+			}
+		}
+
+		return handler(request)
+	}
+}
+
 // Description:
 //
 //	Initializes the development/info api.
 //	This api is used to retrieve internal information about the revx proxy service.
+//	The api is restricted to the clients allowed by the admin filter, by default localhost.
+//	The readiness endpoint is polled by load balancers, so it is only subject to the global filter.
 func InitRevxApi() {
-	Router.Handle("GET", "revx/info", HandleInfo)
-	Router.Handle("GET", "revx/config", HandleConfig)
+	filter, err := ipfilter.NewAdminFilter(config.Global.IpFilter)
+
+	if err != nil {
+		log.Fatalf("api: unable to create admin filter: %s", err)
+	}
+
+	readyFilter, err := ipfilter.NewFilter(config.Global.IpFilter, config.ConfigReverseProxyServerIpFilter{})
+
+	if err != nil {
+		log.Fatalf("api: unable to create readiness filter: %s", err)
+	}
+
+	handle := func(method string, path string, handler router.RouterHandlerFunc) {
+		Router.Handle(method, path, restrictHandler(filter, handler))
+	}
+
+	handle("GET", "revx/info", HandleInfo)
+	handle("GET", "revx/config", HandleConfig)
+	Router.Handle("GET", "revx/ready", restrictHandler(readyFilter, HandleReady))
+
+	handle("GET", "revx/inspect", HandleInspect)
+	handle("GET", "revx/inspect/:name", HandleInspectByName)
+
+	handle("GET", "revx/apikeys", HandleApiKeys)
+	handle("GET", "revx/waf", HandleWaf)
 
-	Router.Handle("GET", "revx/inspect", HandleInspect)
-	Router.Handle("GET", "revx/inspect/:name", HandleInspectByName)
+	handle("GET", "revx/log", HandleLogLevels)
+	handle("PUT", "revx/log", HandleSetDefaultLogLevel)
+	handle("PUT", "revx/log/:subsystem", HandleSetSubsystemLogLevel)
+	handle("DELETE", "revx/log/:subsystem", HandleResetSubsystemLogLevel)
 }
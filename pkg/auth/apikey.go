@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The header carrying the api key, if none is configured.
+const defaultApiKeyHeader string = "X-Api-Key"
+
+// Description:
+//
+//	Creates a middleware, which requires a valid api key for every request.
+//	Every key has its own rate limit and daily quota, shared by all servers using the same key file.
+//	The key is removed from the request, before it is passed to the upstreams.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the key file cannot be loaded.
+func ApiKeyMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	apiKey := conf.ApiKey
+
+	if apiKey.File == "" {
+		return nil, fmt.Errorf("auth: api key authentication requires a file")
+	}
+
+	store, err := sharedApiKeyStore(apiKey.File)
+
+	if err != nil {
+		return nil, err
+	}
+
+	header := apiKey.Header
+
+	if header == "" {
+		header = defaultApiKeyHeader
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			if apiKey.NameHeader != "" {
+				request.Header.Del(apiKey.NameHeader)
+			}
+
+			key := request.Header.Get(header)
+			request.Header.Del(header)
+
+			if apiKey.QueryParameter != "" {
+				rawQuery, value, found := removeQueryParameter(request.URL.RawQuery, apiKey.QueryParameter)
+
+				if found {
+					if key == "" {
+						key = value
+					}
+
+					request.URL.RawQuery = rawQuery
+				}
+			}
+
+			if key == "" {
+				proxy.WriteError(response, request, http.StatusUnauthorized, "API key required.")
+				return
+			}
+
+			name, result, wait := store.check(key, conf.Name, time.Now())
+
+			switch result {
+			case apiKeyInvalid:
+				log.Debugf("auth: invalid api key: %s", conf.Name)
+				proxy.WriteError(response, request, http.StatusUnauthorized, "Invalid API key.")
+
+				return
+			case apiKeyForbidden:
+				log.Debugf("auth: api key not allowed: %s: %s", conf.Name, name)
+				proxy.WriteError(response, request, http.StatusForbidden, "API key not allowed.")
+
+				return
+			case apiKeyRateLimited:
+				log.Debugf("auth: api key rate limit exceeded: %s: %s", conf.Name, name)
+
+				response.Header().Set("Retry-After", retryAfter(wait))
+				proxy.WriteError(response, request, http.StatusTooManyRequests, "Rate limit exceeded.")
+
+				return
+			case apiKeyQuotaExceeded:
+				log.Debugf("auth: api key daily quota exceeded: %s: %s", conf.Name, name)
+
+				response.Header().Set("Retry-After", retryAfter(wait))
+				proxy.WriteError(response, request, http.StatusTooManyRequests, "Daily quota exceeded.")
+
+				return
+			}
+
+			if apiKey.NameHeader != "" {
+				request.Header.Set(apiKey.NameHeader, name)
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Removes all occurrences of a parameter from a raw query string.
+//	The other parameters are kept unchanged, i.e. in their order and encoding.
+//
+// Parameters:
+//
+//	rawQuery 	The raw query string.
+//	name 		The decoded parameter name.
+//
+// Returns:
+//
+//	The query string without the parameter, the decoded value of its first occurrence,
+//	and whether the parameter was found.
+func removeQueryParameter(rawQuery string, name string) (string, string, bool) {
+	pairs := strings.Split(rawQuery, "&")
+	kept := make([]string, 0, len(pairs))
+	value := ""
+	found := false
+
+	for _, pair := range pairs {
+		encodedName, encodedValue, _ := strings.Cut(pair, "=")
+		decodedName, err := url.QueryUnescape(encodedName)
+
+		if err != nil || decodedName != name {
+			kept = append(kept, pair)
+			continue
+		}
+
+		if !found {
+			value, _ = url.QueryUnescape(encodedValue)
+		}
+
+		found = true
+	}
+
+	return strings.Join(kept, "&"), value, found
+}
+
+// Description:
+//
+//	Formats the value of a Retry-After header.
+//
+// Parameters:
+//
+//	wait The time until the request may be retried.
+//
+// Returns:
+//
+//	The time in whole seconds, rounded up.
+func retryAfter(wait time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10)
+}
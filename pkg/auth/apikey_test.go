@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestRemoveQueryParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+		query    string
+		value    string
+		found    bool
+	}{
+		{name: "empty", rawQuery: "", query: ""},
+		{name: "missing", rawQuery: "a=1&b=2", query: "a=1&b=2"},
+		{name: "only parameter", rawQuery: "api_key=k", query: "", value: "k", found: true},
+		{name: "keeps order and encoding", rawQuery: "z=1&api_key=k&a=%20b+c&b=%2f", query: "z=1&a=%20b+c&b=%2f", value: "k", found: true},
+		{name: "encoded name", rawQuery: "a=1&api%5Fkey=k", query: "a=1", value: "k", found: true},
+		{name: "encoded value", rawQuery: "api_key=k%2B1+2", query: "", value: "k+1 2", found: true},
+		{name: "all occurrences, first value", rawQuery: "api_key=k1&a=1&api_key=k2", query: "a=1", value: "k1", found: true},
+		{name: "without value", rawQuery: "api_key&a=1", query: "a=1", value: "", found: true},
+		{name: "prefix of another name", rawQuery: "api_keys=k&xapi_key=k", query: "api_keys=k&xapi_key=k"},
+		{name: "keeps empty and invalid pairs", rawQuery: "a=%zz&&api_key=k&b", query: "a=%zz&&b", value: "k", found: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, value, found := removeQueryParameter(test.rawQuery, "api_key")
+
+			if query != test.query || value != test.value || found != test.found {
+				t.Fatalf("expected %q %q %t, got %q %q %t", test.query, test.value, test.found, query, value, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The minimum time between two checks of a key file for changes.
+const apiKeyCheckInterval = 1 * time.Second
+
+// Description:
+//
+//	The result of an api key check.
+type apiKeyResult int
+
+// Enum declarations.
+const (
+	// The key is valid and the request is counted.
+	apiKeyAccepted apiKeyResult = iota
+
+	// The key does not exist.
+	apiKeyInvalid
+
+	// The key is not allowed to access the server.
+	apiKeyForbidden
+
+	// The rate limit of the key is exceeded.
+	apiKeyRateLimited
+
+	// The daily quota of the key is exceeded.
+	apiKeyQuotaExceeded
+)
+
+// Description:
+//
+//	The content of a key file.
+type apiKeyFile struct {
+	Keys []apiKeyFileEntry `yaml:"keys"`
+}
+
+// Description:
+//
+//	A key of a key file.
+type apiKeyFileEntry struct {
+	Name       string   `yaml:"name"`
+	Key        string   `yaml:"key"`
+	KeySha256  string   `yaml:"key-sha256"`
+	Servers    []string `yaml:"servers"`
+	RateLimit  uint32   `yaml:"rate-limit"`
+	Burst      uint32   `yaml:"burst"`
+	DailyQuota uint64   `yaml:"daily-quota"`
+}
+
+// Description:
+//
+//	An api key.
+type apiKey struct {
+	name       string
+	servers    map[string]bool
+	rateLimit  float64
+	burst      float64
+	dailyQuota uint64
+	usage      *apiKeyUsage
+}
+
+// Description:
+//
+//	The usage of an api key, including its rate limiter state.
+//	Usage is tracked by key name, so it is kept, when the key file is reloaded.
+type apiKeyUsage struct {
+	mutex         sync.Mutex
+	tokens        float64
+	refilled      time.Time
+	requests      uint64
+	rateLimited   uint64
+	quotaExceeded uint64
+	day           string
+	dayRequests   uint64
+	servers       map[string]uint64
+}
+
+// Description:
+//
+//	Represents the usage of an api key, as returned by the admin api.
+type ApiKeyUsageInfo struct {
+	File          string            `json:"file"`          // The key file.
+	Name          string            `json:"name"`          // The key name.
+	RateLimit     uint32            `json:"rateLimit"`     // The allowed requests per second, 0 if unlimited.
+	DailyQuota    uint64            `json:"dailyQuota"`    // The allowed requests per day, 0 if unlimited.
+	Requests      uint64            `json:"requests"`      // The accepted requests since revx started.
+	RateLimited   uint64            `json:"rateLimited"`   // The requests rejected by the rate limit.
+	QuotaExceeded uint64            `json:"quotaExceeded"` // The requests rejected by the daily quota.
+	Day           string            `json:"day"`           // The current quota day (UTC).
+	DayRequests   uint64            `json:"dayRequests"`   // The accepted requests of the current day.
+	Servers       map[string]uint64 `json:"servers"`       // The accepted requests by server.
+}
+
+// Description:
+//
+//	The keys of a key file.
+//	The file is checked for changes at most once per second and reloaded, when it changes.
+//	All methods are safe for concurrent use.
+type apiKeyStore struct {
+	mutex   sync.Mutex
+	path    string
+	keys    map[string]*apiKey
+	usage   map[string]*apiKeyUsage
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// All key stores by path, shared by the servers using the same key file.
+var apiKeyStores = struct {
+	mutex  sync.Mutex
+	stores map[string]*apiKeyStore
+}{stores: map[string]*apiKeyStore{}}
+
+// Description:
+//
+//	Gets the key store of a key file.
+//	The store is created and loaded, if the file is used for the first time.
+//
+// Parameters:
+//
+//	path The path of the key file.
+//
+// Returns:
+//
+//	The key store, or an error, if the file cannot be loaded.
+func sharedApiKeyStore(path string) (*apiKeyStore, error) {
+	apiKeyStores.mutex.Lock()
+	defer apiKeyStores.mutex.Unlock()
+
+	store, exists := apiKeyStores.stores[path]
+
+	if exists {
+		return store, nil
+	}
+
+	store = &apiKeyStore{path: path, usage: map[string]*apiKeyUsage{}}
+	err := store.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyStores.stores[path] = store
+	return store, nil
+}
+
+// Description:
+//
+//	Gets the usage of all api keys.
+//
+// Returns:
+//
+//	The usage of all keys, sorted by file and name.
+func ApiKeyUsage() []ApiKeyUsageInfo {
+	apiKeyStores.mutex.Lock()
+	stores := make([]*apiKeyStore, 0, len(apiKeyStores.stores))
+
+	for _, store := range apiKeyStores.stores {
+		stores = append(stores, store)
+	}
+
+	apiKeyStores.mutex.Unlock()
+
+	infos := []ApiKeyUsageInfo{}
+
+	for _, store := range stores {
+		infos = append(infos, store.snapshot()...)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].File != infos[j].File {
+			return infos[i].File < infos[j].File
+		}
+
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// Description:
+//
+//	Checks an api key and counts the request.
+//
+// Parameters:
+//
+//	key 	The api key.
+//	server 	The name of the requested server.
+//	now 	The current time.
+//
+// Returns:
+//
+//	The key name, the result of the check, and the time until the request may be retried, if it was rejected by a limit.
+func (store *apiKeyStore) check(key string, server string, now time.Time) (string, apiKeyResult, time.Duration) {
+	digest := sha256.Sum256([]byte(key))
+
+	store.mutex.Lock()
+	store.refresh()
+	entry, exists := store.keys[hex.EncodeToString(digest[:])]
+	store.mutex.Unlock()
+
+	if !exists {
+		return "", apiKeyInvalid, 0
+	}
+
+	if len(entry.servers) > 0 && !entry.servers[server] {
+		return entry.name, apiKeyForbidden, 0
+	}
+
+	usage := entry.usage
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+
+	day := now.UTC().Format("2006-01-02")
+
+	if usage.day != day {
+		usage.day = day
+		usage.dayRequests = 0
+	}
+
+	if entry.dailyQuota > 0 && usage.dayRequests >= entry.dailyQuota {
+		usage.quotaExceeded++
+
+		midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return entry.name, apiKeyQuotaExceeded, midnight.Sub(now)
+	}
+
+	if entry.rateLimit > 0 {
+		if usage.refilled.IsZero() {
+			usage.tokens = entry.burst
+		} else {
+			usage.tokens += now.Sub(usage.refilled).Seconds() * entry.rateLimit
+		}
+
+		if usage.tokens > entry.burst {
+			usage.tokens = entry.burst
+		}
+
+		usage.refilled = now
+
+		if usage.tokens < 1 {
+			usage.rateLimited++
+
+			wait := time.Duration((1 - usage.tokens) / entry.rateLimit * float64(time.Second))
+			return entry.name, apiKeyRateLimited, wait
+		}
+
+		usage.tokens--
+	}
+
+	usage.requests++
+	usage.dayRequests++
+	usage.servers[server]++
+
+	return entry.name, apiKeyAccepted, 0
+}
+
+// Description:
+//
+//	Gets the usage of all keys of the store.
+//
+// Returns:
+//
+//	The usage of all keys.
+func (store *apiKeyStore) snapshot() []ApiKeyUsageInfo {
+	store.mutex.Lock()
+	keys := make([]*apiKey, 0, len(store.keys))
+
+	for _, key := range store.keys {
+		keys = append(keys, key)
+	}
+
+	store.mutex.Unlock()
+
+	infos := make([]ApiKeyUsageInfo, 0, len(keys))
+	today := time.Now().UTC().Format("2006-01-02")
+
+	for _, key := range keys {
+		usage := key.usage
+		usage.mutex.Lock()
+
+		info := ApiKeyUsageInfo{
+			File:          store.path,
+			Name:          key.name,
+			RateLimit:     uint32(key.rateLimit),
+			DailyQuota:    key.dailyQuota,
+			Requests:      usage.requests,
+			RateLimited:   usage.rateLimited,
+			QuotaExceeded: usage.quotaExceeded,
+			Day:           today,
+			Servers:       map[string]uint64{},
+		}
+
+		if usage.day == today {
+			info.DayRequests = usage.dayRequests
+		}
+
+		for server, requests := range usage.servers {
+			info.Servers[server] = requests
+		}
+
+		usage.mutex.Unlock()
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// Description:
+//
+//	Reloads the key file, if it changed since it was last loaded.
+//	If the file cannot be reloaded, the previous keys are kept.
+//	Must be called with the mutex held.
+func (store *apiKeyStore) refresh() {
+	if time.Since(store.checked) < apiKeyCheckInterval {
+		return
+	}
+
+	store.checked = time.Now()
+	info, err := os.Stat(store.path)
+
+	if err != nil {
+		log.Warnf("auth: unable to check api key file: %s", err)
+		return
+	}
+
+	if info.ModTime().Equal(store.modTime) && info.Size() == store.size {
+		return
+	}
+
+	err = store.load()
+
+	if err != nil {
+		log.Warnf("auth: unable to reload api key file, keeping previous keys: %s", err)
+		return
+	}
+
+	log.Infof("auth: reloaded api key file: %s", store.path)
+}
+
+// Description:
+//
+//	Loads the key file.
+//	Must be called with the mutex held, or before the store is shared.
+//
+// Returns:
+//
+//	An error, if the file cannot be read or contains invalid keys.
+func (store *apiKeyStore) load() error {
+	info, err := os.Stat(store.path)
+
+	if err != nil {
+		return fmt.Errorf("auth: unable to read api key file: %s", err)
+	}
+
+	data, err := os.ReadFile(store.path)
+
+	if err != nil {
+		return fmt.Errorf("auth: unable to read api key file: %s", err)
+	}
+
+	file := apiKeyFile{}
+	err = yaml.Unmarshal(data, &file)
+
+	if err != nil {
+		return fmt.Errorf("auth: invalid api key file: %s: %s", store.path, err)
+	}
+
+	keys := map[string]*apiKey{}
+	names := map[string]bool{}
+
+	for index, entry := range file.Keys {
+		hash, err := apiKeyHash(entry)
+
+		if err != nil {
+			return fmt.Errorf("auth: invalid api key file: %s: key %d: %s", store.path, index+1, err)
+		}
+
+		if names[entry.Name] {
+			return fmt.Errorf("auth: invalid api key file: %s: duplicate key name: %s", store.path, entry.Name)
+		}
+
+		if _, exists := keys[hash]; exists {
+			return fmt.Errorf("auth: invalid api key file: %s: duplicate key: %s", store.path, entry.Name)
+		}
+
+		key := &apiKey{
+			name:       entry.Name,
+			servers:    map[string]bool{},
+			rateLimit:  float64(entry.RateLimit),
+			burst:      float64(entry.Burst),
+			dailyQuota: entry.DailyQuota,
+		}
+
+		if key.burst < key.rateLimit {
+			key.burst = key.rateLimit
+		}
+
+		for _, server := range entry.Servers {
+			key.servers[server] = true
+		}
+
+		key.usage = store.usage[entry.Name]
+
+		if key.usage == nil {
+			key.usage = &apiKeyUsage{servers: map[string]uint64{}}
+			store.usage[entry.Name] = key.usage
+		}
+
+		names[entry.Name] = true
+		keys[hash] = key
+	}
+
+	store.keys = keys
+	store.modTime = info.ModTime()
+	store.size = info.Size()
+	store.checked = time.Now()
+
+	return nil
+}
+
+// Description:
+//
+//	Gets the SHA-256 hash of a key of a key file.
+//
+// Parameters:
+//
+//	entry The key.
+//
+// Returns:
+//
+//	The hex encoded hash, or an error, if the key is invalid.
+func apiKeyHash(entry apiKeyFileEntry) (string, error) {
+	if entry.Name == "" {
+		return "", fmt.Errorf("missing name")
+	}
+
+	if (entry.Key == "") == (entry.KeySha256 == "") {
+		return "", fmt.Errorf("exactly one of key and key-sha256 is required: %s", entry.Name)
+	}
+
+	if entry.Key != "" {
+		digest := sha256.Sum256([]byte(entry.Key))
+		return hex.EncodeToString(digest[:]), nil
+	}
+
+	hash := strings.ToLower(entry.KeySha256)
+	decoded, err := hex.DecodeString(hash)
+
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid key-sha256: %s", entry.Name)
+	}
+
+	return hash, nil
+}
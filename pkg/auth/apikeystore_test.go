@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The key file of the api key tests.
+const testApiKeyFile = `
+keys:
+  - name: open
+    key: open-key
+  - name: billing
+    key: billing-key
+    servers: [billing]
+  - name: limited
+    key: limited-key
+    rate-limit: 2
+    burst: 3
+  - name: quota
+    key: quota-key
+    daily-quota: 2
+  - name: hashed
+    key-sha256: A4AE87B73FA5645E6AEE415A6F72BE4DCBD99D057A7B40CB1B867C181D179260
+`
+
+// Description:
+//
+//	Creates a key store of a key file.
+//
+// Parameters:
+//
+//	t 		The test.
+//	content The content of the key file.
+//
+// Returns:
+//
+//	The key store, or an error, if the file is invalid.
+func testApiKeyStore(t *testing.T, content string) (*apiKeyStore, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "apikeys.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &apiKeyStore{path: path, usage: map[string]*apiKeyUsage{}}
+	return store, store.load()
+}
+
+func TestApiKeyStoreCheck(t *testing.T) {
+	type step struct {
+		key    string
+		server string
+		offset time.Duration
+		name   string
+		result apiKeyResult
+		wait   time.Duration
+	}
+
+	// Shortly before midnight UTC, so the quota day changes within the test.
+	start := time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "unknown key",
+			steps: []step{
+				{key: "wrong", server: "billing", result: apiKeyInvalid},
+				{key: "", server: "billing", result: apiKeyInvalid},
+			},
+		},
+		{
+			name: "unrestricted key",
+			steps: []step{
+				{key: "open-key", server: "billing", name: "open", result: apiKeyAccepted},
+				{key: "open-key", server: "invoices", name: "open", result: apiKeyAccepted},
+			},
+		},
+		{
+			name: "hashed key",
+			steps: []step{
+				{key: "hashed-key", server: "billing", name: "hashed", result: apiKeyAccepted},
+				{key: "A4AE87B73FA5645E6AEE415A6F72BE4DCBD99D057A7B40CB1B867C181D179260", server: "billing", result: apiKeyInvalid},
+			},
+		},
+		{
+			name: "server restriction",
+			steps: []step{
+				{key: "billing-key", server: "billing", name: "billing", result: apiKeyAccepted},
+				{key: "billing-key", server: "invoices", name: "billing", result: apiKeyForbidden},
+			},
+		},
+		{
+			name: "rate limit burst and refill",
+			steps: []step{
+				{key: "limited-key", name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", name: "limited", result: apiKeyRateLimited, wait: 500 * time.Millisecond},
+				{key: "limited-key", offset: 250 * time.Millisecond, name: "limited", result: apiKeyRateLimited, wait: 250 * time.Millisecond},
+				{key: "limited-key", offset: 500 * time.Millisecond, name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", offset: 500 * time.Millisecond, name: "limited", result: apiKeyRateLimited, wait: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "rate limit refill is capped by the burst",
+			steps: []step{
+				{key: "limited-key", name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", offset: time.Hour, name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", offset: time.Hour, name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", offset: time.Hour, name: "limited", result: apiKeyAccepted},
+				{key: "limited-key", offset: time.Hour, name: "limited", result: apiKeyRateLimited, wait: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "daily quota and day rollover",
+			steps: []step{
+				{key: "quota-key", name: "quota", result: apiKeyAccepted},
+				{key: "quota-key", offset: 10 * time.Second, name: "quota", result: apiKeyAccepted},
+				{key: "quota-key", offset: 20 * time.Second, name: "quota", result: apiKeyQuotaExceeded, wait: 40 * time.Second},
+				{key: "quota-key", offset: 59 * time.Second, name: "quota", result: apiKeyQuotaExceeded, wait: time.Second},
+				{key: "quota-key", offset: 60 * time.Second, name: "quota", result: apiKeyAccepted},
+				{key: "quota-key", offset: 61 * time.Second, name: "quota", result: apiKeyAccepted},
+				{key: "quota-key", offset: 62 * time.Second, name: "quota", result: apiKeyQuotaExceeded, wait: 24*time.Hour - 2*time.Second},
+			},
+		},
+		{
+			name: "rejected requests do not count towards the quota",
+			steps: []step{
+				{key: "quota-key", server: "billing", name: "quota", result: apiKeyAccepted},
+				{key: "quota-key", server: "billing", name: "quota", result: apiKeyAccepted},
+				{key: "quota-key", server: "billing", name: "quota", result: apiKeyQuotaExceeded, wait: time.Minute},
+				{key: "quota-key", server: "billing", offset: time.Minute, name: "quota", result: apiKeyAccepted},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := testApiKeyStore(t, testApiKeyFile)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for index, step := range test.steps {
+				name, result, wait := store.check(step.key, step.server, start.Add(step.offset))
+
+				if name != step.name || result != step.result || wait != step.wait {
+					t.Fatalf("step %d: expected %q %d %s, got %q %d %s", index+1, step.name, step.result, step.wait, name, result, wait)
+				}
+			}
+		})
+	}
+}
+
+func TestApiKeyStoreLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "valid", content: testApiKeyFile},
+		{name: "empty", content: ""},
+		{name: "missing name", content: "keys: [{key: a}]", err: "missing name"},
+		{name: "missing key", content: "keys: [{name: a}]", err: "exactly one of key and key-sha256"},
+		{name: "key and hash", content: "keys: [{name: a, key: a, key-sha256: a}]", err: "exactly one of key and key-sha256"},
+		{name: "invalid hash", content: "keys: [{name: a, key-sha256: abc}]", err: "invalid key-sha256"},
+		{name: "duplicate name", content: "keys: [{name: a, key: a}, {name: a, key: b}]", err: "duplicate key name"},
+		{name: "duplicate key", content: "keys: [{name: a, key: a}, {name: b, key: a}]", err: "duplicate key"},
+		{name: "malformed yaml", content: "keys: {", err: "invalid api key file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testApiKeyStore(t, test.content)
+
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
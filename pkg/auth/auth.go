@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The auth subsystem logger.
+var log = logging.NewLogger("auth")
+
+// Description:
+//
+//	A constructor of an authentication middleware.
+type middlewareFactory struct {
+	enabled func(conf config.ConfigReverseProxyServer) bool
+	create  func(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error)
+}
+
+// All authentication middlewares, in the order they check a request.
+var middlewareFactories = []middlewareFactory{
+	{
+		enabled: func(conf config.ConfigReverseProxyServer) bool { return conf.ClientAuth.Enabled },
+		create:  ClientCertMiddleware,
+	},
+	{
+		enabled: func(conf config.ConfigReverseProxyServer) bool { return conf.BasicAuth.Enabled },
+		create:  BasicAuthMiddleware,
+	},
+	{
+		enabled: func(conf config.ConfigReverseProxyServer) bool { return conf.ApiKey.Enabled },
+		create:  ApiKeyMiddleware,
+	},
+	{
+		enabled: func(conf config.ConfigReverseProxyServer) bool { return conf.Jwt.Enabled },
+		create:  JwtMiddleware,
+	},
+	{
+		enabled: func(conf config.ConfigReverseProxyServer) bool { return conf.ForwardAuth.Enabled },
+		create:  ForwardAuthMiddleware,
+	},
+	{
+		enabled: func(conf config.ConfigReverseProxyServer) bool { return conf.Oidc.Enabled },
+		create:  OidcMiddleware,
+	},
+}
+
+// Description:
+//
+//	Creates all authentication middlewares configured for a server.
+//	The middlewares are ordered innermost first, so a request is checked by them in the order of the factories.
+//	The client certificate headers are removed from the requests of every server, before any middleware is applied.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middlewares, or an error, if a middleware cannot be created.
+func Middlewares(conf config.ConfigReverseProxyServer) ([]router.RouterProxyMiddlewareFunc, error) {
+	middlewares := []router.RouterProxyMiddlewareFunc{}
+
+	for index := len(middlewareFactories) - 1; index >= 0; index-- {
+		factory := middlewareFactories[index]
+
+		if !factory.enabled(conf) {
+			continue
+		}
+
+		middleware, err := factory.create(conf)
+
+		if err != nil {
+			return nil, err
+		}
+
+		middlewares = append(middlewares, middleware)
+	}
+
+	middlewares = append(middlewares, stripClientCertHeadersMiddleware())
+
+	return middlewares, nil
+}
+
+// Description:
+//
+//	Adds all certificates of a PEM encoded CA bundle to a certificate pool.
+//
+// Parameters:
+//
+//	pool 	The certificate pool.
+//	path 	The path of the CA bundle.
+//
+// Returns:
+//
+//	An error, if the file cannot be read or contains no certificates.
+func AppendCertsFromFile(pool *x509.CertPool, path string) error {
+	bundle, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("auth: unable to read ca file: %s", err)
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return fmt.Errorf("auth: no certificates found in ca file: %s", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The realm used, if none is configured.
+const defaultRealm string = "revx"
+
+// Description:
+//
+//	Creates a middleware, which requires valid basic authentication credentials for every request.
+//	Requests without valid credentials are rejected with 401 and a basic authentication challenge.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the users cannot be loaded.
+func BasicAuthMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	basicAuth := conf.BasicAuth
+
+	if basicAuth.File == "" && len(basicAuth.Users) == 0 {
+		return nil, fmt.Errorf("auth: basic authentication requires a file or inline users")
+	}
+
+	passwords, err := newHtpasswd(basicAuth.File, basicAuth.Users)
+
+	if err != nil {
+		return nil, err
+	}
+
+	realm := basicAuth.Realm
+
+	if realm == "" {
+		realm = defaultRealm
+	}
+
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", strings.ReplaceAll(realm, `"`, `'`))
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			if basicAuth.UsernameHeader != "" {
+				request.Header.Del(basicAuth.UsernameHeader)
+			}
+
+			username, password, ok := request.BasicAuth()
+
+			if !ok || !passwords.verify(username, password) {
+				if ok {
+					log.Debugf("auth: invalid basic authentication credentials: %s: %s", conf.Name, username)
+				}
+
+				response.Header().Set("WWW-Authenticate", challenge)
+				proxy.WriteError(response, request, http.StatusUnauthorized, "Unauthorized.")
+
+				return
+			}
+
+			if basicAuth.UsernameHeader != "" {
+				request.Header.Set(basicAuth.UsernameHeader, username)
+			}
+
+			if info := proxy.RequestInfo(request); info != nil {
+				info.User = username
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The headers carrying the details of verified client certificates to the upstreams.
+const (
+	HeaderClientCertSubject     string = "X-Client-Cert-Subject"
+	HeaderClientCertIssuer      string = "X-Client-Cert-Issuer"
+	HeaderClientCertSans        string = "X-Client-Cert-Sans"
+	HeaderClientCertFingerprint string = "X-Client-Cert-Fingerprint"
+	HeaderClientCert            string = "X-Client-Cert"
+)
+
+// All client certificate headers, removed from incoming requests.
+var clientCertHeaders = []string{
+	HeaderClientCertSubject,
+	HeaderClientCertIssuer,
+	HeaderClientCertSans,
+	HeaderClientCertFingerprint,
+	HeaderClientCert,
+}
+
+// Description:
+//
+//	Creates a middleware, which requires a valid client certificate for every request.
+//	Certificates are verified against the CA of the server, so servers can trust different CAs,
+//	while sharing the same listener. Requests without an allowed certificate are rejected with 403.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the CA cannot be loaded.
+func ClientCertMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	clientAuth := conf.ClientAuth
+	roots := x509.NewCertPool()
+
+	err := AppendCertsFromFile(roots, clientAuth.CaFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.Global.Tls.Enabled {
+		log.Warnf("auth: client certificates require tls, all requests will be rejected: %s", conf.Name)
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			if request.TLS == nil || len(request.TLS.PeerCertificates) == 0 {
+				proxy.WriteError(response, request, http.StatusForbidden, "Client certificate required.")
+				return
+			}
+
+			certificates := request.TLS.PeerCertificates
+			intermediates := x509.NewCertPool()
+
+			for _, certificate := range certificates[1:] {
+				intermediates.AddCert(certificate)
+			}
+
+			_, err := certificates[0].Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+
+			if err != nil {
+				log.Debugf("auth: invalid client certificate: %s: %s", conf.Name, err)
+				proxy.WriteError(response, request, http.StatusForbidden, "Invalid client certificate.")
+				return
+			}
+
+			if !clientCertAllowed(certificates[0], clientAuth) {
+				log.Debugf("auth: client certificate not allowed: %s: %s", conf.Name, certificates[0].Subject)
+				proxy.WriteError(response, request, http.StatusForbidden, "Client certificate not allowed.")
+				return
+			}
+
+			if clientAuth.ForwardHeaders {
+				setClientCertHeaders(request.Header, certificates[0])
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates a middleware, which removes the client certificate headers from incoming requests.
+//	It is applied to every server, so clients cannot forge the headers for upstreams,
+//	which are shared with protected servers.
+//
+// Returns:
+//
+//	The middleware.
+func stripClientCertHeadersMiddleware() router.RouterProxyMiddlewareFunc {
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			for _, header := range clientCertHeaders {
+				request.Header.Del(header)
+			}
+
+			handler(request, response)
+		}
+	}
+}
+
+// Description:
+//
+//	Checks whether a client certificate is allowed by the subject and SAN allowlists.
+//
+// Parameters:
+//
+//	certificate The verified client certificate.
+//	conf 		The client certificate authentication configuration.
+//
+// Returns:
+//
+//	True, if the certificate is allowed.
+func clientCertAllowed(certificate *x509.Certificate, conf config.ConfigReverseProxyServerClientAuth) bool {
+	if len(conf.AllowedSubjects) == 0 && len(conf.AllowedSans) == 0 {
+		return true
+	}
+
+	subject := certificate.Subject.String()
+
+	for _, allowed := range conf.AllowedSubjects {
+		if allowed == subject {
+			return true
+		}
+	}
+
+	for _, san := range clientCertSans(certificate) {
+		for _, allowed := range conf.AllowedSans {
+			if allowed == san {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Gets all subject alternative names of a certificate.
+//
+// Parameters:
+//
+//	certificate The certificate.
+//
+// Returns:
+//
+//	The DNS names, email addresses, IP addresses and URIs of the certificate.
+func clientCertSans(certificate *x509.Certificate) []string {
+	sans := []string{}
+	sans = append(sans, certificate.DNSNames...)
+	sans = append(sans, certificate.EmailAddresses...)
+
+	for _, address := range certificate.IPAddresses {
+		sans = append(sans, address.String())
+	}
+
+	for _, uri := range certificate.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	return sans
+}
+
+// Description:
+//
+//	Sets the client certificate headers.
+//
+// Parameters:
+//
+//	header 		The request headers.
+//	certificate The verified client certificate.
+func setClientCertHeaders(header http.Header, certificate *x509.Certificate) {
+	fingerprint := sha256.Sum256(certificate.Raw)
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+
+	header.Set(HeaderClientCertSubject, certificate.Subject.String())
+	header.Set(HeaderClientCertIssuer, certificate.Issuer.String())
+	header.Set(HeaderClientCertSans, strings.Join(clientCertSans(certificate), ","))
+	header.Set(HeaderClientCertFingerprint, hex.EncodeToString(fingerprint[:]))
+	header.Set(HeaderClientCert, url.QueryEscape(string(encoded)))
+}
@@ -0,0 +1,364 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/ipfilter"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// Constant declarations.
+const (
+	// The default time to wait for the authorization service.
+	forwardAuthDefaultTimeout = 5 * time.Second
+
+	// The maximum size of a response body of the authorization service.
+	forwardAuthMaxBodySize = 64 * 1024
+
+	// The maximum amount of cached authorization decisions.
+	forwardAuthCacheSize = 10000
+)
+
+// The request headers copied to the subrequest, if none are configured.
+var defaultForwardAuthRequestHeaders = []string{"Authorization", "Cookie"}
+
+// The response headers of the authorization service, which are never passed to the client.
+var forwardAuthHopHeaders = []string{
+	"Connection",
+	"Content-Length",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Description:
+//
+//	A decision of the authorization service.
+type forwardAuthDecision struct {
+	allowed  bool
+	status   int
+	header   http.Header
+	body     []byte
+	upstream http.Header
+	expires  time.Time
+}
+
+// Description:
+//
+//	Authorizes requests by subrequests to an external authorization service.
+//	Decisions are cached by the method, url and copied headers of a request.
+type forwardAuthorizer struct {
+	conf           config.ConfigReverseProxyServerForwardAuth
+	client         *http.Client
+	method         string
+	requestHeaders []string
+	cacheTtl       time.Duration
+	mutex          sync.Mutex
+	cache          map[string]*forwardAuthDecision
+}
+
+// Description:
+//
+//	Creates a middleware, which authorizes every request by a subrequest to an external authorization service.
+//	Requests are passed to the upstreams, if the authorization service responds with 2xx.
+//	Otherwise, the response of the authorization service is returned to the client.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the authorization service url is invalid.
+func ForwardAuthMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	authorizer, err := newForwardAuthorizer(conf.ForwardAuth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			for _, header := range authorizer.conf.ResponseHeaders {
+				request.Header.Del(header)
+			}
+
+			decision, err := authorizer.authorize(request)
+
+			if err != nil {
+				log.Warnf("auth: authorization service unavailable: %s: %s%s", conf.Name, err, proxy.RequestIdSuffix(request))
+				proxy.WriteError(response, request, http.StatusServiceUnavailable, "Authorization service unavailable.")
+
+				return
+			}
+
+			if !decision.allowed {
+				for name, values := range decision.header {
+					response.Header()[name] = values
+				}
+
+				response.WriteHeader(decision.status)
+				response.Write(decision.body)
+
+				return
+			}
+
+			for name, values := range decision.upstream {
+				request.Header[name] = values
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates a new authorizer.
+//
+// Parameters:
+//
+//	conf The forward authentication configuration.
+//
+// Returns:
+//
+//	The authorizer, or an error, if the authorization service url is invalid.
+func newForwardAuthorizer(conf config.ConfigReverseProxyServerForwardAuth) (*forwardAuthorizer, error) {
+	target, err := url.Parse(conf.Url)
+
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("auth: invalid forward authentication url: %s", conf.Url)
+	}
+
+	timeout := time.Duration(conf.Timeout) * time.Millisecond
+
+	if timeout == 0 {
+		timeout = forwardAuthDefaultTimeout
+	}
+
+	authorizer := &forwardAuthorizer{
+		conf:           conf,
+		method:         conf.Method,
+		requestHeaders: conf.RequestHeaders,
+		cacheTtl:       time.Duration(conf.CacheTtl) * time.Millisecond,
+		cache:          map[string]*forwardAuthDecision{},
+		client: &http.Client{
+			Timeout: timeout,
+
+			// Redirects, e.g. to a login page, are returned to the client.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	if authorizer.method == "" {
+		authorizer.method = http.MethodGet
+	}
+
+	if len(authorizer.requestHeaders) == 0 {
+		authorizer.requestHeaders = defaultForwardAuthRequestHeaders
+	}
+
+	return authorizer, nil
+}
+
+// Description:
+//
+//	Gets the authorization decision for a request.
+//	Uses a cached decision, if there is one.
+//
+// Parameters:
+//
+//	request The request to authorize.
+//
+// Returns:
+//
+//	The decision, or an error, if the authorization service cannot be reached or times out.
+func (authorizer *forwardAuthorizer) authorize(request *http.Request) (*forwardAuthDecision, error) {
+	subrequest, err := authorizer.subrequest(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := ""
+
+	if authorizer.cacheTtl > 0 {
+		key = forwardAuthCacheKey(subrequest)
+
+		if decision := authorizer.cached(key); decision != nil {
+			return decision, nil
+		}
+	}
+
+	response, err := authorizer.client.Do(subrequest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	decision := &forwardAuthDecision{
+		allowed:  response.StatusCode >= 200 && response.StatusCode < 300,
+		status:   response.StatusCode,
+		upstream: http.Header{},
+		expires:  time.Now().Add(authorizer.cacheTtl),
+	}
+
+	if decision.allowed {
+		for _, name := range authorizer.conf.ResponseHeaders {
+			if values := response.Header.Values(name); len(values) > 0 {
+				decision.upstream[http.CanonicalHeaderKey(name)] = values
+			}
+		}
+	} else {
+		decision.header = response.Header.Clone()
+
+		for _, name := range forwardAuthHopHeaders {
+			decision.header.Del(name)
+		}
+
+		decision.body, err = io.ReadAll(io.LimitReader(response.Body, forwardAuthMaxBodySize))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Server errors are returned to the client, but never cached.
+	if authorizer.cacheTtl > 0 && response.StatusCode < http.StatusInternalServerError {
+		authorizer.store(key, decision)
+	}
+
+	return decision, nil
+}
+
+// Description:
+//
+//	Creates the subrequest for a request.
+//	The subrequest describes the original request in the X-Forwarded-* headers
+//	and carries the configured request headers.
+//
+// Parameters:
+//
+//	request The request to authorize.
+//
+// Returns:
+//
+//	The subrequest, or an error, if it cannot be created.
+func (authorizer *forwardAuthorizer) subrequest(request *http.Request) (*http.Request, error) {
+	subrequest, err := http.NewRequestWithContext(request.Context(), authorizer.method, authorizer.conf.Url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	protocol := "http"
+
+	if request.TLS != nil {
+		protocol = "https"
+	}
+
+	subrequest.Header.Set("X-Forwarded-Method", request.Method)
+	subrequest.Header.Set("X-Forwarded-Proto", protocol)
+	subrequest.Header.Set("X-Forwarded-Host", request.Host)
+	subrequest.Header.Set("X-Forwarded-Uri", request.URL.RequestURI())
+
+	subrequest.Header.Set("X-Forwarded-For", ipfilter.RequestClientIp(request))
+
+	for _, name := range authorizer.requestHeaders {
+		if values := request.Header.Values(name); len(values) > 0 {
+			subrequest.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	return subrequest, nil
+}
+
+// Description:
+//
+//	Gets a cached decision.
+//
+// Parameters:
+//
+//	key The cache key.
+//
+// Returns:
+//
+//	The decision, or nil, if there is no unexpired decision.
+func (authorizer *forwardAuthorizer) cached(key string) *forwardAuthDecision {
+	authorizer.mutex.Lock()
+	defer authorizer.mutex.Unlock()
+
+	decision, exists := authorizer.cache[key]
+
+	if !exists || time.Now().After(decision.expires) {
+		return nil
+	}
+
+	return decision
+}
+
+// Description:
+//
+//	Caches a decision.
+//	If the cache is full, expired decisions are removed first. If it is still full, it is cleared.
+//
+// Parameters:
+//
+//	key 		The cache key.
+//	decision 	The decision.
+func (authorizer *forwardAuthorizer) store(key string, decision *forwardAuthDecision) {
+	authorizer.mutex.Lock()
+	defer authorizer.mutex.Unlock()
+
+	if len(authorizer.cache) >= forwardAuthCacheSize {
+		now := time.Now()
+
+		for cachedKey, cached := range authorizer.cache {
+			if now.After(cached.expires) {
+				delete(authorizer.cache, cachedKey)
+			}
+		}
+
+		if len(authorizer.cache) >= forwardAuthCacheSize {
+			authorizer.cache = map[string]*forwardAuthDecision{}
+		}
+	}
+
+	authorizer.cache[key] = decision
+}
+
+// Description:
+//
+//	Computes the cache key of a subrequest, covering its method and all of its headers.
+//
+// Parameters:
+//
+//	subrequest The subrequest.
+//
+// Returns:
+//
+//	The cache key.
+func forwardAuthCacheKey(subrequest *http.Request) string {
+	builder := strings.Builder{}
+	builder.WriteString(subrequest.Method)
+	subrequest.Header.Write(&builder)
+
+	digest := sha256.Sum256([]byte(builder.String()))
+	return hex.EncodeToString(digest[:])
+}
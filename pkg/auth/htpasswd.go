@@ -0,0 +1,360 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Constant declarations.
+const (
+	// The minimum time between two checks of an htpasswd file for changes.
+	htpasswdCheckInterval = 1 * time.Second
+
+	// The maximum amount of cached successful password verifications.
+	htpasswdCacheSize = 1024
+
+	// The bcrypt hash verified for unknown users, so they take as long as known users.
+	htpasswdDummyHash = "$2a$10$kGYn.JN5kxm.F51S/QXWQukvYDaiwoEHlsCnMrHIEBloSnWws7kO2"
+)
+
+// The random key of the verification cache keys, so the cache does not contain plain password digests.
+var htpasswdCacheKey = func() []byte {
+	key := make([]byte, 32)
+
+	_, err := rand.Read(key)
+
+	if err != nil {
+		panic(fmt.Sprintf("auth: unable to create htpasswd cache key: %s", err))
+	}
+
+	return key
+}()
+
+// Description:
+//
+//	A set of users with password hashes, read from an htpasswd file and inline users.
+//	Supported hashes are bcrypt ($2a$, $2b$, $2y$) and SHA-1 ({SHA}).
+//	The file is checked for changes at most once per second and reloaded, when it changes.
+//	All methods are safe for concurrent use.
+type htpasswd struct {
+	mutex   sync.Mutex
+	path    string
+	inline  map[string]string
+	users   map[string]string
+	modTime time.Time
+	size    int64
+	checked time.Time
+	cache   map[string]struct{}
+}
+
+// Description:
+//
+//	Creates a new set of users.
+//
+// Parameters:
+//
+//	path 	The path of the htpasswd file, or empty, if only inline users are used.
+//	lines 	The inline users in htpasswd format.
+//
+// Returns:
+//
+//	The set of users, or an error, if the file cannot be read or an inline user is invalid.
+func newHtpasswd(path string, lines []string) (*htpasswd, error) {
+	inline, err := parseHtpasswd(lines, "inline users")
+
+	if err != nil {
+		return nil, err
+	}
+
+	passwords := &htpasswd{path: path, inline: inline}
+	passwords.reset(map[string]string{})
+
+	if path == "" {
+		return passwords, nil
+	}
+
+	err = passwords.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return passwords, nil
+}
+
+// Description:
+//
+//	Verifies the password of a user.
+//
+// Parameters:
+//
+//	username The username.
+//	password The password.
+//
+// Returns:
+//
+//	True, if the user exists and the password matches.
+func (passwords *htpasswd) verify(username string, password string) bool {
+	passwords.mutex.Lock()
+	passwords.refresh()
+
+	hash, exists := passwords.users[username]
+	key := cacheKey(username, password)
+	_, cached := passwords.cache[key]
+
+	passwords.mutex.Unlock()
+
+	if !exists {
+		verifyPassword(htpasswdDummyHash, password)
+		return false
+	}
+
+	if cached {
+		return true
+	}
+
+	if !verifyPassword(hash, password) {
+		return false
+	}
+
+	passwords.mutex.Lock()
+	defer passwords.mutex.Unlock()
+
+	// The users may have been reloaded in the meantime.
+	if passwords.users[username] != hash {
+		return false
+	}
+
+	if len(passwords.cache) >= htpasswdCacheSize {
+		passwords.cache = map[string]struct{}{}
+	}
+
+	passwords.cache[key] = struct{}{}
+	return true
+}
+
+// Description:
+//
+//	Creates the verification cache key of a user and password.
+//
+// Parameters:
+//
+//	username The username.
+//	password The password.
+//
+// Returns:
+//
+//	The hex encoded HMAC-SHA256 of the username and password.
+func cacheKey(username string, password string) string {
+	mac := hmac.New(sha256.New, htpasswdCacheKey)
+	mac.Write([]byte(username + "\x00" + password))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Description:
+//
+//	Reloads the htpasswd file, if it changed since it was last loaded.
+//	If the file cannot be reloaded, the previous users are kept.
+//	Must be called with the mutex held.
+func (passwords *htpasswd) refresh() {
+	if passwords.path == "" || time.Since(passwords.checked) < htpasswdCheckInterval {
+		return
+	}
+
+	passwords.checked = time.Now()
+	info, err := os.Stat(passwords.path)
+
+	if err != nil {
+		log.Warnf("auth: unable to check htpasswd file: %s", err)
+		return
+	}
+
+	if info.ModTime().Equal(passwords.modTime) && info.Size() == passwords.size {
+		return
+	}
+
+	err = passwords.load()
+
+	if err != nil {
+		log.Warnf("auth: unable to reload htpasswd file, keeping previous users: %s", err)
+		return
+	}
+
+	log.Infof("auth: reloaded htpasswd file: %s", passwords.path)
+}
+
+// Description:
+//
+//	Loads the htpasswd file.
+//	Must be called with the mutex held, or before the set of users is shared.
+//
+// Returns:
+//
+//	An error, if the file cannot be read or contains invalid lines.
+func (passwords *htpasswd) load() error {
+	info, err := os.Stat(passwords.path)
+
+	if err != nil {
+		return fmt.Errorf("auth: unable to read htpasswd file: %s", err)
+	}
+
+	file, err := os.Open(passwords.path)
+
+	if err != nil {
+		return fmt.Errorf("auth: unable to read htpasswd file: %s", err)
+	}
+
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if scanner.Err() != nil {
+		return fmt.Errorf("auth: unable to read htpasswd file: %s", scanner.Err())
+	}
+
+	users, err := parseHtpasswd(lines, passwords.path)
+
+	if err != nil {
+		return err
+	}
+
+	passwords.modTime = info.ModTime()
+	passwords.size = info.Size()
+	passwords.checked = time.Now()
+	passwords.reset(users)
+
+	return nil
+}
+
+// Description:
+//
+//	Replaces the users of the file and clears the verification cache.
+//	Inline users take precedence over users of the file.
+//
+// Parameters:
+//
+//	users The users of the file.
+func (passwords *htpasswd) reset(users map[string]string) {
+	for username, hash := range passwords.inline {
+		users[username] = hash
+	}
+
+	passwords.users = users
+	passwords.cache = map[string]struct{}{}
+}
+
+// Description:
+//
+//	Parses lines in htpasswd format, i.e. username:hash.
+//	Empty lines and comments are skipped, users with unsupported hashes are skipped with a warning.
+//
+// Parameters:
+//
+//	lines 	The lines.
+//	source 	The source of the lines, used in messages.
+//
+// Returns:
+//
+//	The password hashes by username, or an error, if a line is malformed.
+func parseHtpasswd(lines []string, source string) (map[string]string, error) {
+	users := map[string]string{}
+
+	for number, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		username, hash, found := strings.Cut(line, ":")
+
+		if !found || username == "" || hash == "" {
+			return nil, fmt.Errorf("auth: malformed htpasswd line %d: %s", number+1, source)
+		}
+
+		if !supportedHash(hash) {
+			log.Warnf("auth: unsupported password hash, skipping user: %s: %s", source, username)
+			continue
+		}
+
+		users[username] = hash
+	}
+
+	return users, nil
+}
+
+// Description:
+//
+//	Checks whether a password hash is supported.
+//
+// Parameters:
+//
+//	hash The password hash.
+//
+// Returns:
+//
+//	True, if the hash is a bcrypt or SHA-1 hash.
+func supportedHash(hash string) bool {
+	return isBcryptHash(hash) || strings.HasPrefix(hash, "{SHA}")
+}
+
+// Description:
+//
+//	Checks whether a password hash is a bcrypt hash.
+//
+// Parameters:
+//
+//	hash The password hash.
+//
+// Returns:
+//
+//	True, if the hash is a bcrypt hash.
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// Description:
+//
+//	Verifies a password against a password hash.
+//
+// Parameters:
+//
+//	hash 		The password hash.
+//	password 	The password.
+//
+// Returns:
+//
+//	True, if the password matches the hash.
+func verifyPassword(hash string, password string) bool {
+	if isBcryptHash(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	if strings.HasPrefix(hash, "{SHA}") {
+		digest := sha1.Sum([]byte(password))
+		expected := "{SHA}" + base64.StdEncoding.EncodeToString(digest[:])
+
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) == 1
+	}
+
+	return false
+}
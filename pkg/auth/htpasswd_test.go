@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The SHA-1 hash of the password "test".
+const testShaHash = "{SHA}qUqP5cyxm6YcTAhz05Hph5gvu9M="
+
+func TestParseHtpasswd(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		users   map[string]string
+		invalid bool
+	}{
+		{
+			name:  "empty",
+			lines: []string{},
+			users: map[string]string{},
+		},
+		{
+			name:  "comments and empty lines",
+			lines: []string{"# users", "", "  ", "alice:" + testShaHash},
+			users: map[string]string{"alice": testShaHash},
+		},
+		{
+			name:  "surrounding whitespace",
+			lines: []string{"  alice:" + testShaHash + "  "},
+			users: map[string]string{"alice": testShaHash},
+		},
+		{
+			name:  "bcrypt variants",
+			lines: []string{"a:$2a$10$x", "b:$2b$10$x", "y:$2y$10$x"},
+			users: map[string]string{"a": "$2a$10$x", "b": "$2b$10$x", "y": "$2y$10$x"},
+		},
+		{
+			name:  "unsupported hashes are skipped",
+			lines: []string{"md5:$apr1$salt$hash", "crypt:abcdefghijklm", "alice:" + testShaHash},
+			users: map[string]string{"alice": testShaHash},
+		},
+		{
+			name:  "hash containing colons",
+			lines: []string{"alice:{SHA}a:b"},
+			users: map[string]string{"alice": "{SHA}a:b"},
+		},
+		{
+			name:    "missing separator",
+			lines:   []string{"alice"},
+			invalid: true,
+		},
+		{
+			name:    "missing username",
+			lines:   []string{":" + testShaHash},
+			invalid: true,
+		},
+		{
+			name:    "missing hash",
+			lines:   []string{"alice:"},
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users, err := parseHtpasswd(test.lines, "test")
+
+			if test.invalid {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", users)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(users) != len(test.users) {
+				t.Fatalf("expected %v, got %v", test.users, users)
+			}
+
+			for username, hash := range test.users {
+				if users[username] != hash {
+					t.Fatalf("expected %v, got %v", test.users, users)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		valid    bool
+	}{
+		{name: "sha matches", hash: testShaHash, password: "test", valid: true},
+		{name: "sha mismatch", hash: testShaHash, password: "wrong"},
+		{name: "sha empty password", hash: testShaHash, password: ""},
+		{name: "sha truncated hash", hash: testShaHash[:len(testShaHash)-1], password: "test"},
+		{name: "bcrypt matches", hash: string(bcryptHash), password: "test", valid: true},
+		{name: "bcrypt mismatch", hash: string(bcryptHash), password: "wrong"},
+		{name: "bcrypt 2y prefix", hash: "$2y$" + string(bcryptHash[4:]), password: "test", valid: true},
+		{name: "dummy hash", hash: htpasswdDummyHash, password: "test"},
+		{name: "unsupported hash", hash: "$apr1$salt$hash", password: "test"},
+		{name: "plain text hash", hash: "test", password: "test"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := verifyPassword(test.hash, test.password); valid != test.valid {
+				t.Fatalf("expected %t, got %t", test.valid, valid)
+			}
+		})
+	}
+}
+
+func TestHtpasswdVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	err := os.WriteFile(path, []byte("alice:"+testShaHash+"\nbob:{SHA}invalid\n"), 0o600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passwords, err := newHtpasswd(path, []string{"bob:" + testShaHash})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		valid    bool
+	}{
+		{name: "file user", username: "alice", password: "test", valid: true},
+		{name: "cached file user", username: "alice", password: "test", valid: true},
+		{name: "wrong password", username: "alice", password: "wrong"},
+		{name: "inline user overrides file user", username: "bob", password: "test", valid: true},
+		{name: "unknown user", username: "carol", password: "test"},
+		{name: "empty username", username: "", password: "test"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := passwords.verify(test.username, test.password); valid != test.valid {
+				t.Fatalf("expected %t, got %t", test.valid, valid)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if cacheKey("alice", "test") != cacheKey("alice", "test") {
+		t.Fatal("expected equal keys for equal credentials")
+	}
+
+	if cacheKey("alice", "test") == cacheKey("alice", "tesT") {
+		t.Fatal("expected different keys for different passwords")
+	}
+}
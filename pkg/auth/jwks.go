@@ -0,0 +1,482 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Constant declarations.
+const (
+	// HMAC using SHA-256.
+	AlgorithmHS256 string = "HS256"
+
+	// RSASSA-PKCS1-v1_5 using SHA-256.
+	AlgorithmRS256 string = "RS256"
+
+	// ECDSA using P-256 and SHA-256.
+	AlgorithmES256 string = "ES256"
+
+	// The default time a key set is cached.
+	jwksDefaultCacheDuration = 5 * time.Minute
+
+	// The minimum time between two reads of a key set, triggered by unknown key ids.
+	jwksMinRefreshInterval = 10 * time.Second
+
+	// The timeout of key set requests.
+	jwksRequestTimeout = 10 * time.Second
+
+	// The maximum size of a key set.
+	jwksMaxSize = 1024 * 1024
+)
+
+// Description:
+//
+//	A key used to verify token signatures.
+type jwtKey struct {
+	id        string
+	algorithm string
+	key       interface{}
+}
+
+// Description:
+//
+//	The keys used to verify the tokens of a server.
+//	Static keys are loaded once, keys of a key set file or url are cached and read again,
+//	once the cache expired or a token refers to an unknown key id.
+//	All methods are safe for concurrent use.
+type jwtKeySet struct {
+	mutex         sync.Mutex
+	static        []jwtKey
+	source        string
+	read          func() ([]byte, error)
+	cacheDuration time.Duration
+	keys          []jwtKey
+	fetched       time.Time
+	attempted     time.Time
+}
+
+// Description:
+//
+//	A JSON web key, as defined in RFC 7517.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	Value     string `json:"k"`
+}
+
+// Description:
+//
+//	Creates the key set of a server.
+//
+// Parameters:
+//
+//	secret 			The HS256 secret, or empty.
+//	publicKeyFile 	The path of a PEM file with public keys, or empty.
+//	jwksFile 		The path of a key set file, or empty.
+//	jwksUrl 		The url of a key set, or empty.
+//	cacheDuration 	The time a key set is cached, 0 for the default.
+//
+// Returns:
+//
+//	The key set, or an error, if no key is configured or a key cannot be loaded.
+func newJwtKeySet(secret string, publicKeyFile string, jwksFile string, jwksUrl string, cacheDuration time.Duration) (*jwtKeySet, error) {
+	set := &jwtKeySet{cacheDuration: cacheDuration}
+
+	if set.cacheDuration == 0 {
+		set.cacheDuration = jwksDefaultCacheDuration
+	}
+
+	if secret != "" {
+		set.static = append(set.static, jwtKey{algorithm: AlgorithmHS256, key: []byte(secret)})
+	}
+
+	if publicKeyFile != "" {
+		keys, err := readPublicKeyFile(publicKeyFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		set.static = append(set.static, keys...)
+	}
+
+	if jwksFile != "" && jwksUrl != "" {
+		return nil, fmt.Errorf("auth: jwks-file and jwks-url are mutually exclusive")
+	}
+
+	if jwksFile != "" {
+		set.source = jwksFile
+		set.read = func() ([]byte, error) {
+			return os.ReadFile(jwksFile)
+		}
+
+		// Key set files are expected to exist at startup.
+		set.attempted = time.Now()
+		err := set.update()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if jwksUrl != "" {
+		set.source = jwksUrl
+		set.read = func() ([]byte, error) {
+			return fetchJwks(jwksUrl)
+		}
+
+		// The key set server may not be available yet, the keys are fetched again on demand.
+		set.attempted = time.Now()
+		set.update()
+	}
+
+	if len(set.static) == 0 && set.read == nil {
+		return nil, fmt.Errorf("auth: jwt authentication requires a secret, public-key-file, jwks-file or jwks-url")
+	}
+
+	return set, nil
+}
+
+// Description:
+//
+//	Gets the keys, which may have signed a token.
+//	Expired key sets are read again in the background, while the cached keys are still used.
+//	If no key matches the key id of the token, the key set is read again immediately.
+//
+// Parameters:
+//
+//	id 			The key id of the token, or empty.
+//	algorithm 	The signature algorithm of the token.
+//
+// Returns:
+//
+//	The candidate keys.
+func (set *jwtKeySet) lookup(id string, algorithm string) []jwtKey {
+	set.mutex.Lock()
+
+	candidates := matchingKeys(set.static, id, algorithm)
+	candidates = append(candidates, matchingKeys(set.keys, id, algorithm)...)
+
+	if set.read == nil || time.Since(set.attempted) < jwksMinRefreshInterval {
+		set.mutex.Unlock()
+		return candidates
+	}
+
+	// Keys may have been rotated since the key set was read.
+	missing := len(set.keys) == 0 || (len(candidates) == 0 && id != "")
+	expired := time.Since(set.fetched) >= set.cacheDuration
+
+	if !missing && !expired {
+		set.mutex.Unlock()
+		return candidates
+	}
+
+	set.attempted = time.Now()
+	set.mutex.Unlock()
+
+	if !missing {
+		go set.update()
+		return candidates
+	}
+
+	set.update()
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	candidates = matchingKeys(set.static, id, algorithm)
+	return append(candidates, matchingKeys(set.keys, id, algorithm)...)
+}
+
+// Description:
+//
+//	Reads the key set again.
+//	If the key set cannot be read, the previous keys are kept.
+//
+// Returns:
+//
+//	An error, if the key set cannot be read.
+func (set *jwtKeySet) update() error {
+	data, err := set.read()
+
+	if err == nil {
+		var keys []jwtKey
+		keys, err = parseJwks(data)
+
+		if err == nil {
+			set.mutex.Lock()
+			set.keys = keys
+			set.fetched = time.Now()
+			set.mutex.Unlock()
+
+			log.Debugf("auth: loaded %d keys from jwks: %s", len(keys), set.source)
+			return nil
+		}
+	}
+
+	err = fmt.Errorf("auth: unable to load jwks: %s: %s", set.source, err)
+	log.Warnf("%s", err)
+
+	return err
+}
+
+// Description:
+//
+//	Selects the keys matching a key id and an algorithm.
+//
+// Parameters:
+//
+//	keys 		The keys.
+//	id 			The key id, or empty to match keys with any id.
+//	algorithm 	The algorithm.
+//
+// Returns:
+//
+//	The matching keys.
+func matchingKeys(keys []jwtKey, id string, algorithm string) []jwtKey {
+	result := []jwtKey{}
+
+	for _, key := range keys {
+		if key.algorithm != algorithm {
+			continue
+		}
+
+		if id != "" && key.id != "" && key.id != id {
+			continue
+		}
+
+		result = append(result, key)
+	}
+
+	return result
+}
+
+// Description:
+//
+//	Fetches a key set.
+//
+// Parameters:
+//
+//	url The url of the key set.
+//
+// Returns:
+//
+//	The key set, or an error, if it cannot be fetched.
+func fetchJwks(url string) ([]byte, error) {
+	client := http.Client{Timeout: jwksRequestTimeout}
+	response, err := client.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", response.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, jwksMaxSize))
+}
+
+// Description:
+//
+//	Parses a JSON web key set.
+//	Keys not used for signatures and keys of unsupported types are skipped.
+//
+// Parameters:
+//
+//	data The key set.
+//
+// Returns:
+//
+//	The keys, or an error, if the key set is malformed.
+func parseJwks(data []byte) ([]jwtKey, error) {
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+
+	err := json.Unmarshal(data, &set)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []jwtKey{}
+
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJwk(jwk)
+
+		if err != nil {
+			log.Warnf("auth: skipping json web key: %s: %s", jwk.KeyId, err)
+			continue
+		}
+
+		if jwk.Algorithm != "" && jwk.Algorithm != key.algorithm {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Description:
+//
+//	Parses a single JSON web key.
+//
+// Parameters:
+//
+//	jwk The JSON web key.
+//
+// Returns:
+//
+//	The key, or an error, if the key is malformed or not supported.
+func parseJwk(jwk jsonWebKey) (jwtKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		modulus, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+
+		if err != nil {
+			return jwtKey{}, fmt.Errorf("invalid modulus: %s", err)
+		}
+
+		exponent, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+
+		if err != nil || len(exponent) == 0 || len(exponent) > 4 {
+			return jwtKey{}, fmt.Errorf("invalid exponent")
+		}
+
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+
+		return jwtKey{id: jwk.KeyId, algorithm: AlgorithmRS256, key: key}, nil
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return jwtKey{}, fmt.Errorf("unsupported curve: %s", jwk.Curve)
+		}
+
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+
+		if errX != nil || errY != nil {
+			return jwtKey{}, fmt.Errorf("invalid coordinates")
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return jwtKey{}, fmt.Errorf("point is not on curve")
+		}
+
+		return jwtKey{id: jwk.KeyId, algorithm: AlgorithmES256, key: key}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.Value)
+
+		if err != nil || len(secret) == 0 {
+			return jwtKey{}, fmt.Errorf("invalid secret")
+		}
+
+		return jwtKey{id: jwk.KeyId, algorithm: AlgorithmHS256, key: secret}, nil
+	}
+
+	return jwtKey{}, fmt.Errorf("unsupported key type: %s", jwk.KeyType)
+}
+
+// Description:
+//
+//	Reads all RSA and EC public keys of a PEM file.
+//	Supports public keys and certificates.
+//
+// Parameters:
+//
+//	path The path of the PEM file.
+//
+// Returns:
+//
+//	The keys, or an error, if the file cannot be read or contains no supported key.
+func readPublicKeyFile(path string) ([]jwtKey, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to read public key file: %s", err)
+	}
+
+	keys := []jwtKey{}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+
+		if block == nil {
+			break
+		}
+
+		var publicKey interface{}
+
+		switch block.Type {
+		case "PUBLIC KEY":
+			publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var certificate *x509.Certificate
+			certificate, err = x509.ParseCertificate(block.Bytes)
+
+			if err == nil {
+				publicKey = certificate.PublicKey
+			}
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("auth: unable to parse public key file: %s: %s", path, err)
+		}
+
+		switch key := publicKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, jwtKey{algorithm: AlgorithmRS256, key: key})
+		case *ecdsa.PublicKey:
+			if key.Curve == elliptic.P256() {
+				keys = append(keys, jwtKey{algorithm: AlgorithmES256, key: key})
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("auth: no supported public keys found in file: %s", path)
+	}
+
+	return keys, nil
+}
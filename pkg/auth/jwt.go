@@ -0,0 +1,459 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The algorithms accepted, if none are configured.
+var defaultAlgorithms = []string{AlgorithmHS256, AlgorithmRS256, AlgorithmES256}
+
+// Description:
+//
+//	Validates JSON web tokens, as defined in RFC 7519.
+type jwtValidator struct {
+	keys       *jwtKeySet
+	algorithms map[string]bool
+	issuer     string
+	audiences  []string
+	leeway     time.Duration
+	required   map[string]string
+	expiring   bool
+}
+
+// Description:
+//
+//	The header of a JSON web token.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+// Description:
+//
+//	Creates a middleware, which requires a valid JSON web token for every request.
+//	Requests without a valid token are rejected with 401, tokens without the required claims with 403.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the keys cannot be loaded.
+func JwtMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	jwt := conf.Jwt
+	validator, err := newJwtValidator(jwt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			for _, header := range jwt.ForwardClaims {
+				request.Header.Del(header)
+			}
+
+			token, found := bearerToken(request)
+
+			if !found {
+				response.Header().Set("WWW-Authenticate", `Bearer realm="revx"`)
+				proxy.WriteError(response, request, http.StatusUnauthorized, "Token required.")
+
+				return
+			}
+
+			claims, err := validator.validate(token, time.Now())
+
+			if err != nil {
+				log.Debugf("auth: invalid token: %s: %s", conf.Name, err)
+
+				response.Header().Set("WWW-Authenticate", `Bearer realm="revx", error="invalid_token"`)
+				proxy.WriteError(response, request, http.StatusUnauthorized, "Invalid token.")
+
+				return
+			}
+
+			err = validator.authorize(claims)
+
+			if err != nil {
+				log.Debugf("auth: insufficient token claims: %s: %s", conf.Name, err)
+
+				response.Header().Set("WWW-Authenticate", `Bearer realm="revx", error="insufficient_scope"`)
+				proxy.WriteError(response, request, http.StatusForbidden, "Insufficient claims.")
+
+				return
+			}
+
+			for claim, header := range jwt.ForwardClaims {
+				value, exists := claims[claim]
+
+				if exists {
+					request.Header.Set(header, claimString(value))
+				}
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates a token validator.
+//
+// Parameters:
+//
+//	conf The JWT authentication configuration.
+//
+// Returns:
+//
+//	The validator, or an error, if the keys cannot be loaded or an algorithm is not supported.
+func newJwtValidator(conf config.ConfigReverseProxyServerJwt) (*jwtValidator, error) {
+	cacheDuration := time.Duration(conf.JwksCacheDuration) * time.Millisecond
+	keys, err := newJwtKeySet(conf.Secret, conf.PublicKeyFile, conf.JwksFile, conf.JwksUrl, cacheDuration)
+
+	if err != nil {
+		return nil, err
+	}
+
+	algorithms := conf.Algorithms
+
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+
+	validator := &jwtValidator{
+		keys:       keys,
+		algorithms: map[string]bool{},
+		issuer:     conf.Issuer,
+		audiences:  conf.Audiences,
+		leeway:     time.Duration(conf.Leeway) * time.Millisecond,
+		required:   conf.RequiredClaims,
+		expiring:   !conf.AllowMissingExpiration,
+	}
+
+	for _, algorithm := range algorithms {
+		switch algorithm {
+		case AlgorithmHS256, AlgorithmRS256, AlgorithmES256:
+			validator.algorithms[algorithm] = true
+		default:
+			return nil, fmt.Errorf("auth: unsupported jwt algorithm: %s", algorithm)
+		}
+	}
+
+	return validator, nil
+}
+
+// Description:
+//
+//	Validates the signature and the registered claims of a token.
+//
+// Parameters:
+//
+//	token 	The compact serialized token.
+//	now 	The current time.
+//
+// Returns:
+//
+//	The claims of the token, or an error, if the token is not valid.
+func (validator *jwtValidator) validate(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	header := jwtHeader{}
+	err := decodeJwtPart(parts[0], &header)
+
+	if err != nil {
+		return nil, fmt.Errorf("malformed header: %s", err)
+	}
+
+	if !validator.algorithms[header.Algorithm] {
+		return nil, fmt.Errorf("algorithm not accepted: %s", header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+
+	for _, key := range validator.keys.lookup(header.KeyId, header.Algorithm) {
+		if verifySignature(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+
+	if !verified {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	claims := map[string]interface{}{}
+	err = decodeJwtPart(parts[1], &claims)
+
+	if err != nil {
+		return nil, fmt.Errorf("malformed claims: %s", err)
+	}
+
+	if expiry, exists := claims["exp"]; exists {
+		seconds, ok := numericDate(expiry)
+
+		if !ok || !now.Add(-validator.leeway).Before(seconds) {
+			return nil, fmt.Errorf("token expired")
+		}
+	} else if validator.expiring {
+		return nil, fmt.Errorf("token without expiration")
+	}
+
+	if notBefore, exists := claims["nbf"]; exists {
+		seconds, ok := numericDate(notBefore)
+
+		if !ok || now.Add(validator.leeway).Before(seconds) {
+			return nil, fmt.Errorf("token not valid yet")
+		}
+	}
+
+	if validator.issuer != "" && claims["iss"] != validator.issuer {
+		return nil, fmt.Errorf("issuer not accepted: %v", claims["iss"])
+	}
+
+	if len(validator.audiences) > 0 && !claimContainsAny(claims["aud"], validator.audiences) {
+		return nil, fmt.Errorf("audience not accepted: %v", claims["aud"])
+	}
+
+	return claims, nil
+}
+
+// Description:
+//
+//	Checks whether the claims of a valid token contain all required claims.
+//
+// Parameters:
+//
+//	claims The claims of the token.
+//
+// Returns:
+//
+//	An error, if a required claim is missing or has another value.
+func (validator *jwtValidator) authorize(claims map[string]interface{}) error {
+	for claim, required := range validator.required {
+		value, exists := claims[claim]
+
+		if !exists {
+			return fmt.Errorf("missing claim: %s", claim)
+		}
+
+		if required != "" && !claimContainsAny(value, []string{required}) {
+			return fmt.Errorf("claim does not match: %s", claim)
+		}
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Gets the bearer token of a request.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The token, and whether the request carries a bearer token.
+func bearerToken(request *http.Request) (string, bool) {
+	authorization := request.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(authorization, " ")
+
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Description:
+//
+//	Decodes a base64url encoded JSON part of a token.
+//	Numbers are kept in their textual representation.
+//
+// Parameters:
+//
+//	part 	The encoded part.
+//	value 	The value to decode into.
+//
+// Returns:
+//
+//	An error, if the part is malformed.
+func decodeJwtPart(part string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(value)
+}
+
+// Description:
+//
+//	Verifies the signature of a token.
+//
+// Parameters:
+//
+//	key 		The key.
+//	signed 		The signed header and claims.
+//	signature 	The signature.
+//
+// Returns:
+//
+//	True, if the signature is valid.
+func verifySignature(key jwtKey, signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch key.algorithm {
+	case AlgorithmHS256:
+		mac := hmac.New(sha256.New, key.key.([]byte))
+		mac.Write(signed)
+
+		return hmac.Equal(mac.Sum(nil), signature)
+	case AlgorithmRS256:
+		return rsa.VerifyPKCS1v15(key.key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case AlgorithmES256:
+		if len(signature) != 64 {
+			return false
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+
+		return ecdsa.Verify(key.key.(*ecdsa.PublicKey), digest[:], r, s)
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Converts a numeric date claim to a point in time.
+//
+// Parameters:
+//
+//	value The claim value, in seconds since the unix epoch.
+//
+// Returns:
+//
+//	The point in time, and whether the claim is a number.
+func numericDate(value interface{}) (time.Time, bool) {
+	number, ok := value.(json.Number)
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	seconds, err := number.Float64()
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// Description:
+//
+//	Checks whether a claim equals any of the given values.
+//	For array claims, any element has to equal any of the values.
+//
+// Parameters:
+//
+//	claim 	The claim value.
+//	values 	The accepted values.
+//
+// Returns:
+//
+//	True, if the claim matches.
+func claimContainsAny(claim interface{}, values []string) bool {
+	elements, isArray := claim.([]interface{})
+
+	if !isArray {
+		elements = []interface{}{claim}
+	}
+
+	for _, element := range elements {
+		text := claimString(element)
+
+		for _, value := range values {
+			if text == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Converts a claim to its header representation.
+//	Arrays are joined by commas, objects are encoded as JSON.
+//
+// Parameters:
+//
+//	claim The claim value.
+//
+// Returns:
+//
+//	The claim as string.
+func claimString(claim interface{}) string {
+	switch value := claim.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	case nil:
+		return ""
+	case []interface{}:
+		elements := make([]string, 0, len(value))
+
+		for _, element := range value {
+			elements = append(elements, claimString(element))
+		}
+
+		return strings.Join(elements, ",")
+	}
+
+	encoded, _ := json.Marshal(claim)
+	return string(encoded)
+}
@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// The shared secret of the test tokens.
+const testJwtSecret = "test-secret"
+
+// Description:
+//
+//	Creates a signed test token.
+//
+// Parameters:
+//
+//	t 		The test.
+//	header 	The token header.
+//	claims 	The token claims.
+//	sign 	Signs the header and claims, or nil for an empty signature.
+//
+// Returns:
+//
+//	The compact serialized token.
+func testJwt(t *testing.T, header map[string]interface{}, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	t.Helper()
+
+	encode := func(value interface{}) string {
+		data, err := json.Marshal(value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	signature := []byte{}
+
+	if sign != nil {
+		signature = sign([]byte(signed))
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Description:
+//
+//	Creates an HS256 signer.
+func hmacSigner(secret []byte) func(signed []byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+
+		return mac.Sum(nil)
+	}
+}
+
+func TestJwtValidatorValidate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecPublic, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublic})
+	ecPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecPublic})
+	path := filepath.Join(t.TempDir(), "keys.pem")
+
+	err = os.WriteFile(path, append(append([]byte{}, rsaPem...), ecPem...), 0o600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaSigner := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return signature
+	}
+
+	ecSigner := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+
+		return signature
+	}
+
+	now := time.Unix(1700000000, 0)
+	hs256 := map[string]interface{}{"alg": AlgorithmHS256, "typ": "JWT"}
+	rs256 := map[string]interface{}{"alg": AlgorithmRS256, "typ": "JWT"}
+	es256 := map[string]interface{}{"alg": AlgorithmES256, "typ": "JWT"}
+
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{"sub": "alice", "exp": now.Unix() + 60}
+
+		for name, value := range extra {
+			if value == nil {
+				delete(result, name)
+				continue
+			}
+
+			result[name] = value
+		}
+
+		return result
+	}
+
+	defaultConf := config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, PublicKeyFile: path}
+
+	tests := []struct {
+		name  string
+		conf  config.ConfigReverseProxyServerJwt
+		token string
+		valid bool
+	}{
+		{
+			name:  "hs256",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(nil), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "rs256",
+			conf:  defaultConf,
+			token: testJwt(t, rs256, claims(nil), rsaSigner),
+			valid: true,
+		},
+		{
+			name:  "es256",
+			conf:  defaultConf,
+			token: testJwt(t, es256, claims(nil), ecSigner),
+			valid: true,
+		},
+		{
+			name:  "wrong secret",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(nil), hmacSigner([]byte("wrong"))),
+		},
+		{
+			name:  "tampered claims",
+			conf:  defaultConf,
+			token: tamper(testJwt(t, hs256, claims(nil), hmacSigner([]byte(testJwtSecret)))),
+		},
+		{
+			name:  "alg none",
+			conf:  defaultConf,
+			token: testJwt(t, map[string]interface{}{"alg": "none"}, claims(nil), nil),
+		},
+		{
+			name:  "alg none without signature part",
+			conf:  defaultConf,
+			token: strings.TrimSuffix(testJwt(t, map[string]interface{}{"alg": "none"}, claims(nil), nil), "."),
+		},
+		{
+			name:  "hs256 signed with the rsa public key",
+			conf:  config.ConfigReverseProxyServerJwt{PublicKeyFile: path},
+			token: testJwt(t, hs256, claims(nil), hmacSigner(rsaPem)),
+		},
+		{
+			name:  "hs256 signed with the rsa public key and a secret configured",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(nil), hmacSigner(rsaPem)),
+		},
+		{
+			name:  "rs256 signature with es256 header",
+			conf:  defaultConf,
+			token: testJwt(t, es256, claims(nil), rsaSigner),
+		},
+		{
+			name:  "algorithm not accepted",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, PublicKeyFile: path, Algorithms: []string{AlgorithmRS256}},
+			token: testJwt(t, hs256, claims(nil), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "expired",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(map[string]interface{}{"exp": now.Unix() - 1}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "expiring now",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(map[string]interface{}{"exp": now.Unix()}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "expired within leeway",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Leeway: 30000},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"exp": now.Unix() - 10}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "exp not a number",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(map[string]interface{}{"exp": "never"}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "missing exp",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(map[string]interface{}{"exp": nil}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "missing exp allowed",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, AllowMissingExpiration: true},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"exp": nil}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "not valid yet",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(map[string]interface{}{"nbf": now.Unix() + 10}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "not valid yet within leeway",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Leeway: 30000},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"nbf": now.Unix() + 10}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "valid since now",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(map[string]interface{}{"nbf": now.Unix()}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "issuer matches",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Issuer: "https://issuer.example.com"},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"iss": "https://issuer.example.com"}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "issuer does not match",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Issuer: "https://issuer.example.com"},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"iss": "https://evil.example.com"}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "issuer missing",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Issuer: "https://issuer.example.com"},
+			token: testJwt(t, hs256, claims(nil), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "audience matches",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Audiences: []string{"api", "web"}},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"aud": "web"}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "audience array contains",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Audiences: []string{"api"}},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"aud": []string{"other", "api"}}), hmacSigner([]byte(testJwtSecret))),
+			valid: true,
+		},
+		{
+			name:  "audience does not match",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Audiences: []string{"api"}},
+			token: testJwt(t, hs256, claims(map[string]interface{}{"aud": []string{"other"}}), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "audience missing",
+			conf:  config.ConfigReverseProxyServerJwt{Secret: testJwtSecret, Audiences: []string{"api"}},
+			token: testJwt(t, hs256, claims(nil), hmacSigner([]byte(testJwtSecret))),
+		},
+		{
+			name:  "malformed token",
+			conf:  defaultConf,
+			token: "not-a-token",
+		},
+		{
+			name:  "malformed signature",
+			conf:  defaultConf,
+			token: testJwt(t, hs256, claims(nil), nil) + "!",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator, err := newJwtValidator(test.conf)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = validator.validate(test.token, now)
+
+			if valid := err == nil; valid != test.valid {
+				t.Fatalf("expected %t, got %t: %v", test.valid, valid, err)
+			}
+		})
+	}
+}
+
+// Description:
+//
+//	Replaces the claims of a token, keeping its header and signature.
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","exp":1700000060}`))
+
+	return strings.Join(parts, ".")
+}
+
+func TestJwtValidatorAuthorize(t *testing.T) {
+	validator, err := newJwtValidator(config.ConfigReverseProxyServerJwt{
+		Secret:         testJwtSecret,
+		RequiredClaims: map[string]string{"role": "admin", "tenant": ""},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		valid  bool
+	}{
+		{name: "all claims", claims: map[string]interface{}{"role": "admin", "tenant": "a"}, valid: true},
+		{name: "array claim", claims: map[string]interface{}{"role": []interface{}{"user", "admin"}, "tenant": "a"}, valid: true},
+		{name: "wrong value", claims: map[string]interface{}{"role": "user", "tenant": "a"}},
+		{name: "missing claim", claims: map[string]interface{}{"role": "admin"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := validator.authorize(test.claims) == nil; valid != test.valid {
+				t.Fatalf("expected %t, got %t", test.valid, valid)
+			}
+		})
+	}
+}
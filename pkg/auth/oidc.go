@@ -0,0 +1,644 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// Constant declarations.
+const (
+	// The default name of the session cookie.
+	oidcDefaultCookieName = "revx_session"
+
+	// The default maximum time of a session.
+	oidcDefaultSessionDuration = 24 * time.Hour
+
+	// The time a login has to be completed in.
+	oidcLoginDuration = 10 * time.Minute
+
+	// The default callback path, relative to the server context.
+	oidcDefaultCallbackPath = "/oauth2/callback"
+
+	// The default logout path, relative to the server context.
+	oidcDefaultLogoutPath = "/oauth2/logout"
+)
+
+// The scopes requested, if none are configured.
+var defaultOidcScopes = []string{"openid", "profile", "email"}
+
+// Description:
+//
+//	The state of a pending login, stored in an encrypted cookie.
+type oidcLoginState struct {
+	State       string `json:"s"`
+	Nonce       string `json:"n"`
+	Verifier    string `json:"v"`
+	RedirectUrl string `json:"c"`
+	ReturnUrl   string `json:"r"`
+}
+
+// Description:
+//
+//	A login session, stored in an encrypted cookie.
+type oidcSession struct {
+	Claims       map[string]string `json:"c,omitempty"`
+	AccessToken  string            `json:"a,omitempty"`
+	RefreshToken string            `json:"r,omitempty"`
+	Expiry       int64             `json:"e"`
+	Issued       int64             `json:"i"`
+}
+
+// Description:
+//
+//	Logs users in at an OpenID Connect identity provider, using the authorization code flow with PKCE.
+type oidcRelyingParty struct {
+	conf            config.ConfigReverseProxyServerOidc
+	name            string
+	provider        *oidcProvider
+	cookies         *cookieStore
+	cookieName      string
+	cookiePath      string
+	scopes          string
+	redirectUrl     string
+	callbackPath    string
+	logoutPath      string
+	sessionDuration time.Duration
+}
+
+// Description:
+//
+//	Creates a middleware, which requires users to log in at an OpenID Connect identity provider.
+//	Unauthenticated browser requests are redirected to the identity provider, other requests are rejected with 401.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the configuration is invalid.
+func OidcMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	party, err := newOidcRelyingParty(conf.Name, conf.Context, conf.Oidc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The identity provider may not be available yet, discovery is retried on demand.
+	go func() {
+		_, _, err := party.provider.discover()
+
+		if err != nil {
+			log.Warnf("auth: oidc discovery failed: %s: %s", conf.Name, err)
+		}
+	}()
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			party.serve(handler, request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates a relying party.
+//
+// Parameters:
+//
+//	name 	The server name.
+//	context The server context path.
+//	conf 	The OpenID Connect configuration.
+//
+// Returns:
+//
+//	The relying party, or an error, if the configuration is invalid.
+func newOidcRelyingParty(name string, context string, conf config.ConfigReverseProxyServerOidc) (*oidcRelyingParty, error) {
+	if conf.Issuer == "" || conf.ClientId == "" {
+		return nil, fmt.Errorf("auth: oidc requires an issuer and a client-id")
+	}
+
+	cookies, err := newCookieStore(conf.CookieSecret)
+
+	if err != nil {
+		return nil, fmt.Errorf("auth: oidc requires a cookie-secret")
+	}
+
+	base := strings.TrimSuffix(context, "/")
+
+	party := &oidcRelyingParty{
+		conf:            conf,
+		name:            name,
+		provider:        newOidcProvider(conf),
+		cookies:         cookies,
+		cookieName:      conf.CookieName,
+		cookiePath:      context,
+		scopes:          strings.Join(conf.Scopes, " "),
+		redirectUrl:     conf.RedirectUrl,
+		callbackPath:    base + oidcDefaultCallbackPath,
+		logoutPath:      conf.LogoutPath,
+		sessionDuration: time.Duration(conf.SessionDuration) * time.Millisecond,
+	}
+
+	if party.cookieName == "" {
+		party.cookieName = oidcDefaultCookieName
+	}
+
+	if party.cookiePath == "" {
+		party.cookiePath = "/"
+	}
+
+	if len(conf.Scopes) == 0 {
+		party.scopes = strings.Join(defaultOidcScopes, " ")
+	}
+
+	if party.logoutPath == "" {
+		party.logoutPath = base + oidcDefaultLogoutPath
+	}
+
+	if party.sessionDuration == 0 {
+		party.sessionDuration = oidcDefaultSessionDuration
+	}
+
+	if party.redirectUrl != "" {
+		redirect, err := url.Parse(party.redirectUrl)
+
+		if err != nil || !redirect.IsAbs() || redirect.Host == "" {
+			return nil, fmt.Errorf("auth: invalid oidc redirect-url: %s", party.redirectUrl)
+		}
+
+		party.callbackPath = redirect.Path
+	}
+
+	for _, path := range []string{party.callbackPath, party.logoutPath} {
+		if path != base && !strings.HasPrefix(path, base+"/") {
+			return nil, fmt.Errorf("auth: oidc path not within server context: %s", path)
+		}
+	}
+
+	return party, nil
+}
+
+// Description:
+//
+//	Handles a request.
+//	Serves the callback and logout endpoints, and passes requests with a valid session to the upstreams.
+//
+// Parameters:
+//
+//	handler 	The next handler.
+//	request 	The request.
+//	response 	The response writer.
+func (party *oidcRelyingParty) serve(handler router.RouterProxyHandlerFunc, request *http.Request, response http.ResponseWriter) {
+	for _, header := range party.conf.ClaimHeaders {
+		request.Header.Del(header)
+	}
+
+	switch request.URL.Path {
+	case party.callbackPath:
+		party.callback(request, response)
+		return
+	case party.logoutPath:
+		party.logout(request, response)
+		return
+	}
+
+	session := &oidcSession{}
+
+	if !party.cookies.read(request, party.cookieName, session) {
+		party.login(request, response)
+		return
+	}
+
+	now := time.Now()
+
+	if now.After(time.Unix(session.Issued, 0).Add(party.sessionDuration)) {
+		party.login(request, response)
+		return
+	}
+
+	if now.After(time.Unix(session.Expiry, 0)) {
+		err := party.refresh(session, now)
+
+		if err != nil {
+			log.Debugf("auth: oidc session refresh failed: %s: %s", party.name, err)
+			party.login(request, response)
+
+			return
+		}
+
+		err = party.writeSession(response, request, session, now)
+
+		if err != nil {
+			log.Warnf("auth: unable to write oidc session: %s: %s%s", party.name, err, proxy.RequestIdSuffix(request))
+			proxy.WriteError(response, request, http.StatusInternalServerError, "Internal server error.")
+
+			return
+		}
+	}
+
+	stripCookies(request, party.cookieName)
+
+	for claim, header := range party.conf.ClaimHeaders {
+		value, exists := session.Claims[claim]
+
+		if exists {
+			request.Header.Set(header, value)
+		}
+	}
+
+	if party.conf.ForwardAccessToken {
+		request.Header.Set("Authorization", "Bearer "+session.AccessToken)
+	}
+
+	handler(request, response)
+}
+
+// Description:
+//
+//	Starts a login by redirecting the user to the identity provider.
+//	Only GET and HEAD requests are redirected, other requests are rejected with 401.
+//
+// Parameters:
+//
+//	request 	The request.
+//	response 	The response writer.
+func (party *oidcRelyingParty) login(request *http.Request, response http.ResponseWriter) {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		proxy.WriteError(response, request, http.StatusUnauthorized, "Login required.")
+		return
+	}
+
+	discovery, _, err := party.provider.discover()
+
+	if err != nil {
+		log.Warnf("auth: oidc discovery failed: %s: %s%s", party.name, err, proxy.RequestIdSuffix(request))
+		proxy.WriteError(response, request, http.StatusServiceUnavailable, "Identity provider unavailable.")
+
+		return
+	}
+
+	state := &oidcLoginState{
+		State:       randomToken(),
+		Nonce:       randomToken(),
+		Verifier:    randomToken(),
+		RedirectUrl: party.callbackUrl(request),
+		ReturnUrl:   request.URL.RequestURI(),
+	}
+
+	err = party.cookies.write(response, request, party.cookieName+"_state", state, party.cookiePath, oidcLoginDuration, request.TLS != nil)
+
+	if err != nil {
+		log.Warnf("auth: unable to write oidc login state: %s: %s%s", party.name, err, proxy.RequestIdSuffix(request))
+		proxy.WriteError(response, request, http.StatusInternalServerError, "Internal server error.")
+
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", party.conf.ClientId)
+	query.Set("redirect_uri", state.RedirectUrl)
+	query.Set("scope", party.scopes)
+	query.Set("state", state.State)
+	query.Set("nonce", state.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	http.Redirect(response, request, discovery.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+}
+
+// Description:
+//
+//	Completes a login.
+//	Exchanges the authorization code for tokens, creates the session and redirects the user to the page the login started at.
+//
+// Parameters:
+//
+//	request 	The callback request.
+//	response 	The response writer.
+func (party *oidcRelyingParty) callback(request *http.Request, response http.ResponseWriter) {
+	stateName := party.cookieName + "_state"
+	state := &oidcLoginState{}
+	found := party.cookies.read(request, stateName, state)
+
+	party.cookies.clear(response, request, stateName, party.cookiePath, request.TLS != nil)
+
+	query := request.URL.Query()
+
+	if !found || subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state.State)) != 1 {
+		log.Debugf("auth: oidc login failed: %s: invalid state", party.name)
+		proxy.WriteError(response, request, http.StatusUnauthorized, "Login failed.")
+
+		return
+	}
+
+	if query.Get("error") != "" || query.Get("code") == "" {
+		log.Debugf("auth: oidc login failed: %s: %s", party.name, query.Get("error"))
+		proxy.WriteError(response, request, http.StatusUnauthorized, "Login failed.")
+
+		return
+	}
+
+	session, err := party.exchange(query.Get("code"), state, time.Now())
+
+	if errors.Is(err, errProviderUnavailable) {
+		log.Warnf("auth: oidc login failed: %s: %s%s", party.name, err, proxy.RequestIdSuffix(request))
+		proxy.WriteError(response, request, http.StatusServiceUnavailable, "Identity provider unavailable.")
+
+		return
+	}
+
+	if err != nil {
+		log.Debugf("auth: oidc login failed: %s: %s", party.name, err)
+		proxy.WriteError(response, request, http.StatusUnauthorized, "Login failed.")
+
+		return
+	}
+
+	err = party.writeSession(response, request, session, time.Now())
+
+	if err != nil {
+		log.Warnf("auth: unable to write oidc session: %s: %s%s", party.name, err, proxy.RequestIdSuffix(request))
+		proxy.WriteError(response, request, http.StatusInternalServerError, "Internal server error.")
+
+		return
+	}
+
+	target := state.ReturnUrl
+
+	// Only local redirects are allowed.
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		target = party.cookiePath
+	}
+
+	http.Redirect(response, request, target, http.StatusFound)
+}
+
+// Description:
+//
+//	Ends a session and redirects the user to the end session endpoint of the identity provider, if there is one.
+//
+// Parameters:
+//
+//	request 	The logout request.
+//	response 	The response writer.
+func (party *oidcRelyingParty) logout(request *http.Request, response http.ResponseWriter) {
+	party.cookies.clear(response, request, party.cookieName, party.cookiePath, request.TLS != nil)
+
+	discovery, _, err := party.provider.discover()
+
+	if err != nil || discovery.EndSessionEndpoint == "" {
+		http.Redirect(response, request, party.cookiePath, http.StatusFound)
+		return
+	}
+
+	separator := "?"
+
+	if strings.Contains(discovery.EndSessionEndpoint, "?") {
+		separator = "&"
+	}
+
+	query := url.Values{}
+	query.Set("client_id", party.conf.ClientId)
+
+	http.Redirect(response, request, discovery.EndSessionEndpoint+separator+query.Encode(), http.StatusFound)
+}
+
+// Description:
+//
+//	Exchanges an authorization code for tokens and creates a session.
+//
+// Parameters:
+//
+//	code 	The authorization code.
+//	state 	The state of the login.
+//	now 	The current time.
+//
+// Returns:
+//
+//	The session, or an error, if the code or the id token is not valid.
+func (party *oidcRelyingParty) exchange(code string, state *oidcLoginState, now time.Time) (*oidcSession, error) {
+	discovery, validator, err := party.provider.discover()
+
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", state.RedirectUrl)
+	form.Set("code_verifier", state.Verifier)
+
+	tokens, err := party.provider.token(discovery, form)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tokens.IdToken == "" {
+		return nil, fmt.Errorf("token response without id token")
+	}
+
+	claims, err := validator.validate(tokens.IdToken, now)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %s", err)
+	}
+
+	if claimString(claims["nonce"]) != state.Nonce {
+		return nil, fmt.Errorf("invalid id token: nonce does not match")
+	}
+
+	session := &oidcSession{Issued: now.Unix()}
+	party.update(session, tokens, claims, now)
+
+	return session, nil
+}
+
+// Description:
+//
+//	Refreshes the access token of a session, using its refresh token.
+//
+// Parameters:
+//
+//	session The session.
+//	now 	The current time.
+//
+// Returns:
+//
+//	An error, if the session has no refresh token or the refresh fails.
+func (party *oidcRelyingParty) refresh(session *oidcSession, now time.Time) error {
+	if session.RefreshToken == "" {
+		return fmt.Errorf("session expired")
+	}
+
+	discovery, validator, err := party.provider.discover()
+
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", session.RefreshToken)
+
+	tokens, err := party.provider.token(discovery, form)
+
+	if err != nil {
+		return err
+	}
+
+	claims := map[string]interface{}(nil)
+
+	// A new id token is optional, see OpenID Connect Core 1.0, section 12.2.
+	if tokens.IdToken != "" {
+		claims, err = validator.validate(tokens.IdToken, now)
+
+		if err != nil {
+			return fmt.Errorf("invalid id token: %s", err)
+		}
+	}
+
+	party.update(session, tokens, claims, now)
+	return nil
+}
+
+// Description:
+//
+//	Updates a session from a token response.
+//	The refresh token and claims are kept, if the response does not contain new ones.
+//
+// Parameters:
+//
+//	session The session.
+//	tokens 	The token response.
+//	claims 	The claims of the id token, or nil.
+//	now 	The current time.
+func (party *oidcRelyingParty) update(session *oidcSession, tokens *oidcTokenResponse, claims map[string]interface{}, now time.Time) {
+	session.AccessToken = tokens.AccessToken
+
+	if tokens.RefreshToken != "" {
+		session.RefreshToken = tokens.RefreshToken
+	}
+
+	if claims != nil {
+		session.Claims = map[string]string{}
+
+		for claim := range party.conf.ClaimHeaders {
+			value, exists := claims[claim]
+
+			if exists {
+				session.Claims[claim] = claimString(value)
+			}
+		}
+	}
+
+	expiry := now.Add(party.sessionDuration)
+	expiresIn, err := tokens.ExpiresIn.Int64()
+
+	if err == nil && expiresIn > 0 {
+		expiry = now.Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	session.Expiry = expiry.Unix()
+}
+
+// Description:
+//
+//	Writes the session cookie, which expires together with the session.
+//
+// Parameters:
+//
+//	response 	The response writer.
+//	request 	The request.
+//	session 	The session.
+//	now 		The current time.
+//
+// Returns:
+//
+//	An error, if the session cannot be encoded.
+func (party *oidcRelyingParty) writeSession(response http.ResponseWriter, request *http.Request, session *oidcSession, now time.Time) error {
+	remaining := time.Unix(session.Issued, 0).Add(party.sessionDuration).Sub(now)
+	return party.cookies.write(response, request, party.cookieName, session, party.cookiePath, remaining, request.TLS != nil)
+}
+
+// Description:
+//
+//	Gets the absolute callback url.
+//	Unless configured, the callback is located on the requested host.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The callback url.
+func (party *oidcRelyingParty) callbackUrl(request *http.Request) string {
+	if party.redirectUrl != "" {
+		return party.redirectUrl
+	}
+
+	scheme := "http"
+
+	if request.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + request.Host + party.callbackPath
+}
+
+// Description:
+//
+//	Removes all cookies with the given name prefix from a request, so they are not passed to the upstreams.
+//
+// Parameters:
+//
+//	request The request.
+//	name 	The cookie name prefix.
+func stripCookies(request *http.Request, name string) {
+	cookies := request.Cookies()
+	request.Header.Del("Cookie")
+
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie.Name, name) {
+			request.AddCookie(cookie)
+		}
+	}
+}
+
+// Description:
+//
+//	Creates a random token, used for the state, nonce and code verifier of a login.
+//
+// Returns:
+//
+//	The base64url encoded token.
+func randomToken() string {
+	token := make([]byte, 32)
+	rand.Read(token)
+
+	return base64.RawURLEncoding.EncodeToString(token)
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Constant declarations.
+const (
+	// The timeout of requests to the identity provider.
+	oidcRequestTimeout = 10 * time.Second
+
+	// The minimum time between two discovery attempts.
+	oidcMinDiscoveryInterval = 10 * time.Second
+
+	// The tolerated clock skew when validating id tokens.
+	oidcLeeway = 60 * time.Second
+
+	// The maximum size of a response of the identity provider.
+	oidcMaxResponseSize = 1024 * 1024
+)
+
+// The error returned, if the identity provider cannot be reached or fails.
+var errProviderUnavailable = errors.New("identity provider unavailable")
+
+// Description:
+//
+//	The provider metadata, as defined in OpenID Connect Discovery 1.0.
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JwksUri               string   `json:"jwks_uri"`
+	EndSessionEndpoint    string   `json:"end_session_endpoint"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Description:
+//
+//	A response of the token endpoint.
+type oidcTokenResponse struct {
+	AccessToken  string      `json:"access_token"`
+	IdToken      string      `json:"id_token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    json.Number `json:"expires_in"`
+}
+
+// Description:
+//
+//	An OpenID Connect identity provider.
+//	The provider metadata is discovered on first use and kept afterwards.
+//	All methods are safe for concurrent use.
+type oidcProvider struct {
+	conf      config.ConfigReverseProxyServerOidc
+	client    *http.Client
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	validator *jwtValidator
+	attempted time.Time
+}
+
+// Description:
+//
+//	Creates an identity provider.
+//
+// Parameters:
+//
+//	conf The OpenID Connect configuration.
+//
+// Returns:
+//
+//	The identity provider.
+func newOidcProvider(conf config.ConfigReverseProxyServerOidc) *oidcProvider {
+	return &oidcProvider{
+		conf: conf,
+		client: &http.Client{
+			Timeout: oidcRequestTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Description:
+//
+//	Gets the provider metadata and the id token validator.
+//	Discovers the provider, if it has not been discovered yet, at most once every 10 seconds.
+//
+// Returns:
+//
+//	The provider metadata and validator, or an error, if the provider has not been discovered.
+func (provider *oidcProvider) discover() (*oidcDiscovery, *jwtValidator, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if provider.discovery != nil {
+		return provider.discovery, provider.validator, nil
+	}
+
+	if time.Since(provider.attempted) < oidcMinDiscoveryInterval {
+		return nil, nil, fmt.Errorf("%w: discovery failed recently", errProviderUnavailable)
+	}
+
+	provider.attempted = time.Now()
+
+	discovery := &oidcDiscovery{}
+	issuer := strings.TrimSuffix(provider.conf.Issuer, "/")
+	err := provider.get(issuer+"/.well-known/openid-configuration", discovery)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, nil, fmt.Errorf("%w: issuer does not match: %s", errProviderUnavailable, discovery.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksUri == "" {
+		return nil, nil, fmt.Errorf("%w: incomplete provider metadata", errProviderUnavailable)
+	}
+
+	algorithms := []string{AlgorithmRS256, AlgorithmES256}
+
+	// Id tokens may be signed with the client secret.
+	if provider.conf.ClientSecret != "" {
+		algorithms = append(algorithms, AlgorithmHS256)
+	}
+
+	validator, err := newJwtValidator(config.ConfigReverseProxyServerJwt{
+		Algorithms: algorithms,
+		Secret:     provider.conf.ClientSecret,
+		JwksUrl:    discovery.JwksUri,
+		Issuer:     discovery.Issuer,
+		Audiences:  []string{provider.conf.ClientId},
+		Leeway:     uint32(oidcLeeway / time.Millisecond),
+
+		// Id tokens must always expire.
+		AllowMissingExpiration: false,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider.discovery = discovery
+	provider.validator = validator
+
+	return discovery, validator, nil
+}
+
+// Description:
+//
+//	Requests tokens from the token endpoint.
+//	The client authenticates using client_secret_basic, unless the provider only supports client_secret_post.
+//
+// Parameters:
+//
+//	discovery 	The provider metadata.
+//	form 		The grant parameters.
+//
+// Returns:
+//
+//	The token response, or an error, if the grant is rejected or the provider is unavailable.
+func (provider *oidcProvider) token(discovery *oidcDiscovery, form url.Values) (*oidcTokenResponse, error) {
+	basic := provider.conf.ClientSecret != ""
+
+	if basic && len(discovery.TokenAuthMethods) > 0 {
+		basic = false
+
+		for _, method := range discovery.TokenAuthMethods {
+			if method == "client_secret_basic" {
+				basic = true
+			}
+		}
+	}
+
+	if !basic {
+		form.Set("client_id", provider.conf.ClientId)
+
+		if provider.conf.ClientSecret != "" {
+			form.Set("client_secret", provider.conf.ClientSecret)
+		}
+	}
+
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	if basic {
+		request.SetBasicAuth(url.QueryEscape(provider.conf.ClientId), url.QueryEscape(provider.conf.ClientSecret))
+	}
+
+	tokens := &oidcTokenResponse{}
+	err = provider.do(request, tokens)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tokens.AccessToken == "" {
+		return nil, fmt.Errorf("token response without access token")
+	}
+
+	return tokens, nil
+}
+
+// Description:
+//
+//	Performs a GET request to the identity provider.
+//
+// Parameters:
+//
+//	target 	The url.
+//	value 	The value to decode the JSON response into.
+//
+// Returns:
+//
+//	An error, if the request fails.
+func (provider *oidcProvider) get(target string, value interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, target, nil)
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	return provider.do(request, value)
+}
+
+// Description:
+//
+//	Performs a request to the identity provider.
+//	Network errors and 5xx responses are reported as an unavailable provider.
+//
+// Parameters:
+//
+//	request The request.
+//	value 	The value to decode the JSON response into.
+//
+// Returns:
+//
+//	An error, if the request fails or the response is not 200.
+func (provider *oidcProvider) do(request *http.Request, value interface{}) error {
+	response, err := provider.client.Do(request)
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", errProviderUnavailable, err)
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, oidcMaxResponseSize))
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", errProviderUnavailable, err)
+	}
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: unexpected status: %d", errProviderUnavailable, response.StatusCode)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, value)
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The maximum size of a single cookie value. Larger values are split into several cookies.
+const cookieChunkSize = 3800
+
+// Description:
+//
+//	Stores values in encrypted cookies.
+//	Values are encoded as JSON and encrypted using AES-256-GCM, authenticated with the cookie name.
+type cookieStore struct {
+	aead cipher.AEAD
+}
+
+// Description:
+//
+//	Creates a new cookie store.
+//
+// Parameters:
+//
+//	secret The secret, from which the encryption key is derived.
+//
+// Returns:
+//
+//	The cookie store, or an error, if the secret is empty.
+func newCookieStore(secret string) (*cookieStore, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("auth: a cookie secret is required")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &cookieStore{aead: aead}, nil
+}
+
+// Description:
+//
+//	Reads and decrypts a value.
+//	Values split into several cookies are joined first.
+//
+// Parameters:
+//
+//	request The request carrying the cookies.
+//	name 	The cookie name.
+//	value 	The value to decode into.
+//
+// Returns:
+//
+//	True, if the cookie exists and could be decrypted.
+func (store *cookieStore) read(request *http.Request, name string, value interface{}) bool {
+	encoded := ""
+
+	for index := 0; ; index++ {
+		cookie, err := request.Cookie(chunkName(name, index))
+
+		if err != nil {
+			break
+		}
+
+		encoded += cookie.Value
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	nonceSize := store.aead.NonceSize()
+
+	if err != nil || len(sealed) < nonceSize {
+		return false
+	}
+
+	plain, err := store.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(plain, value) == nil
+}
+
+// Description:
+//
+//	Encrypts and writes a value.
+//	Cookies left over from a larger previous value are removed.
+//
+// Parameters:
+//
+//	response 	The response writer.
+//	request 	The request, used to find left over cookies.
+//	name 		The cookie name.
+//	value 		The value.
+//	path 		The cookie path.
+//	maxAge 		The lifetime of the cookie.
+//	secure 		Whether the cookie is only sent over https.
+//
+// Returns:
+//
+//	An error, if the value cannot be encoded.
+func (store *cookieStore) write(response http.ResponseWriter, request *http.Request, name string, value interface{}, path string, maxAge time.Duration, secure bool) error {
+	plain, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, store.aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(store.aead.Seal(nonce, nonce, plain, []byte(name)))
+	index := 0
+
+	for ; len(encoded) > 0; index++ {
+		size := cookieChunkSize
+
+		if size > len(encoded) {
+			size = len(encoded)
+		}
+
+		http.SetCookie(response, newCookie(chunkName(name, index), encoded[:size], path, int(maxAge.Seconds()), secure))
+		encoded = encoded[size:]
+	}
+
+	for ; ; index++ {
+		_, err := request.Cookie(chunkName(name, index))
+
+		if err != nil {
+			return nil
+		}
+
+		http.SetCookie(response, newCookie(chunkName(name, index), "", path, -1, secure))
+	}
+}
+
+// Description:
+//
+//	Removes a value, including all of its cookies.
+//
+// Parameters:
+//
+//	response 	The response writer.
+//	request 	The request carrying the cookies.
+//	name 		The cookie name.
+//	path 		The cookie path.
+//	secure 		Whether the cookie is only sent over https.
+func (store *cookieStore) clear(response http.ResponseWriter, request *http.Request, name string, path string, secure bool) {
+	for index := 0; ; index++ {
+		_, err := request.Cookie(chunkName(name, index))
+
+		if err != nil {
+			return
+		}
+
+		http.SetCookie(response, newCookie(chunkName(name, index), "", path, -1, secure))
+	}
+}
+
+// Description:
+//
+//	Creates a session cookie, which is not accessible by scripts.
+//
+// Parameters:
+//
+//	name 	The cookie name.
+//	value 	The cookie value.
+//	path 	The cookie path.
+//	maxAge 	The lifetime of the cookie in seconds, or a negative value to delete the cookie.
+//	secure 	Whether the cookie is only sent over https.
+//
+// Returns:
+//
+//	The cookie.
+func newCookie(name string, value string, path string, maxAge int, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Description:
+//
+//	Gets the name of a cookie chunk.
+//
+// Parameters:
+//
+//	name 	The cookie name.
+//	index 	The chunk index.
+//
+// Returns:
+//
+//	The name of the first chunk is the cookie name itself, further chunks are numbered.
+func chunkName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+
+	return name + "_" + strconv.Itoa(index)
+}
@@ -1,11 +1,17 @@
 package boot
 
 import (
-	"github.com/revx-official/output/log"
+	"github.com/revx-official/revx/pkg/accesslog"
 	"github.com/revx-official/revx/pkg/api"
 	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/stream"
+	"github.com/revx-official/revx/pkg/tracing"
 )
 
+// The boot subsystem logger.
+var log = logging.NewLogger("boot")
+
 // Description:
 //
 //	Initializes the reverse proxy.
@@ -25,9 +31,33 @@ func Boot(configFilePath string) {
 		log.Warnf("boot: falling back to default configuration ...")
 	}
 
+	err = logging.InitLogging(config.Global.Log)
+
+	if err != nil {
+		log.Fatalf("boot: unable to initialize logging: %s", err)
+	}
+
+	err = accesslog.InitAccessLog(config.Global.AccessLog)
+
+	if err != nil {
+		log.Fatalf("boot: unable to initialize access log: %s", err)
+	}
+
+	err = tracing.InitTracing(config.Global.Tracing)
+
+	if err != nil {
+		log.Fatalf("boot: unable to initialize tracing: %s", err)
+	}
+
 	api.InitApi()
 	api.InitRevxApi()
 	api.InitProxyApi()
 
+	err = stream.InitStreams()
+
+	if err != nil {
+		log.Fatalf("boot: unable to initialize stream servers: %s", err)
+	}
+
 	api.Boot()
 }
@@ -17,6 +17,249 @@ type ConfigRevx struct {
 
 	// The server configuration.
 	Servers []ConfigReverseProxyServer `yaml:"servers" json:"servers,omitempty"`
+
+	// The stream server configuration.
+	Streams []ConfigStreamServer `yaml:"streams" json:"streams,omitempty"`
+
+	// The TLS configuration of the listener.
+	Tls ConfigTls `yaml:"tls" json:"tls"`
+
+	// The HTTP/2 configuration of the listener.
+	Http2 ConfigHttp2 `yaml:"http2" json:"http2"`
+
+	// The access log configuration.
+	AccessLog ConfigAccessLog `yaml:"access-log" json:"accessLog"`
+
+	// The log configuration.
+	Log ConfigLog `yaml:"log" json:"log"`
+
+	// The tracing configuration.
+	Tracing ConfigTracing `yaml:"tracing" json:"tracing"`
+
+	// The request id configuration.
+	RequestId ConfigRequestId `yaml:"request-id" json:"requestId"`
+
+	// The shutdown configuration.
+	Shutdown ConfigShutdown `yaml:"shutdown" json:"shutdown"`
+
+	// The binary upgrade configuration.
+	Upgrade ConfigUpgrade `yaml:"upgrade" json:"upgrade"`
+
+	// The client ip filter applied to all requests.
+	IpFilter ConfigIpFilter `yaml:"ip-filter" json:"ipFilter"`
+}
+
+// Description:
+//
+//	Represents the global client ip filter configuration.
+type ConfigIpFilter struct {
+
+	// The addresses or CIDR ranges allowed to send requests.
+	// If empty, all clients not denied are allowed.
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+
+	// The addresses or CIDR ranges denied to send requests.
+	// Deny rules take precedence over allow rules.
+	Deny []string `yaml:"deny" json:"deny,omitempty"`
+
+	// The response status of rejected requests.
+	// Defaults to 403.
+	Status int `yaml:"status" json:"status"`
+
+	// The addresses or CIDR ranges of proxies in front of revx.
+	// For requests of trusted proxies, the client ip is taken from the X-Forwarded-For header.
+	TrustedProxies []string `yaml:"trusted-proxies" json:"trustedProxies,omitempty"`
+
+	// The addresses or CIDR ranges allowed to access the revx api.
+	// Defaults to localhost.
+	AdminAllow []string `yaml:"admin-allow" json:"adminAllow,omitempty"`
+}
+
+// Description:
+//
+//	Represents the TLS configuration of the listener.
+type ConfigTls struct {
+
+	// Whether to serve TLS instead of cleartext HTTP.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The path of the PEM encoded certificate file.
+	// May contain the full certificate chain.
+	CertFile string `yaml:"cert-file" json:"certFile"`
+
+	// The path of the PEM encoded private key file.
+	KeyFile string `yaml:"key-file" json:"keyFile"`
+}
+
+// Description:
+//
+//	Represents the HTTP/2 configuration of the listener.
+type ConfigHttp2 struct {
+
+	// Whether to negotiate HTTP/2 on TLS connections.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Whether to accept HTTP/2 over cleartext connections (h2c).
+	// Both prior knowledge and the HTTP/1.1 upgrade mechanism are supported.
+	H2c bool `yaml:"h2c" json:"h2c"`
+}
+
+// Description:
+//
+//	Represents the binary upgrade configuration.
+type ConfigUpgrade struct {
+
+	// The maximum time in milliseconds to wait for the new process to report readiness.
+	// If exceeded, the new process is killed and the current process keeps serving.
+	ReadyTimeout uint32 `yaml:"ready-timeout" json:"readyTimeout"`
+}
+
+// Description:
+//
+//	Represents the graceful shutdown configuration.
+type ConfigShutdown struct {
+
+	// The time in milliseconds between failing readiness and closing the listener.
+	// Gives load balancers time to stop sending new requests.
+	Delay uint32 `yaml:"delay" json:"delay"`
+
+	// The maximum time in milliseconds to wait for in-flight requests and websockets to complete.
+	// Remaining connections are closed afterwards.
+	DrainTimeout uint32 `yaml:"drain-timeout" json:"drainTimeout"`
+}
+
+// Description:
+//
+//	Represents the request id configuration.
+type ConfigRequestId struct {
+
+	// Whether to assign a request id to every proxied request.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The header carrying the request id, e.g. X-Request-Id.
+	// The request id is forwarded to the upstream and returned to the client in this header.
+	Header string `yaml:"header" json:"header"`
+
+	// Whether to accept request ids sent by the client in the request id header.
+	// If disabled, a new request id is always generated.
+	TrustIncoming bool `yaml:"trust-incoming" json:"trustIncoming"`
+}
+
+// Description:
+//
+//	Represents the distributed tracing configuration.
+type ConfigTracing struct {
+
+	// Whether to create and export spans for proxied requests.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The OTLP/HTTP traces endpoint of the collector, e.g. http://localhost:4318/v1/traces.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+
+	// Additional headers sent with every export request, e.g. for authentication.
+	Headers map[string]string `yaml:"headers" json:"-"`
+
+	// The service name reported to the collector.
+	ServiceName string `yaml:"service-name" json:"serviceName"`
+
+	// The default sample rate between 0 and 1 for new traces.
+	// Requests continuing a trace follow the sampling decision of the caller.
+	SampleRate float64 `yaml:"sample-rate" json:"sampleRate"`
+
+	// The maximum amount of spans per export request.
+	BatchSize uint32 `yaml:"batch-size" json:"batchSize"`
+
+	// The interval in milliseconds in which pending spans are exported.
+	FlushInterval uint32 `yaml:"flush-interval" json:"flushInterval"`
+}
+
+// Description:
+//
+//	Represents the log configuration.
+type ConfigLog struct {
+
+	// The default log level.
+	// Supported levels are trace, debug, info, warn, error and fatal.
+	Level string `yaml:"level" json:"level"`
+
+	// The log levels of individual subsystems, e.g. proxy, health or api.
+	// Subsystems without an explicit level use the default log level.
+	Levels map[string]string `yaml:"levels" json:"levels,omitempty"`
+
+	// The log output.
+	// Either stdout, stderr, syslog or a file path.
+	Output string `yaml:"output" json:"output"`
+
+	// The log file rotation configuration.
+	// Only applies if the output is a file path.
+	Rotation ConfigLogRotation `yaml:"rotation" json:"rotation"`
+
+	// The syslog configuration.
+	// Only applies if the output is syslog.
+	Syslog ConfigLogSyslog `yaml:"syslog" json:"syslog"`
+}
+
+// Description:
+//
+//	Represents a log file rotation configuration.
+type ConfigLogRotation struct {
+
+	// The maximum size of a log file in megabytes, before it is rotated.
+	// A value of 0 disables size based rotation.
+	MaxSize uint32 `yaml:"max-size" json:"maxSize"`
+
+	// The time based rotation interval.
+	// Either hourly, daily or empty to disable time based rotation.
+	Interval string `yaml:"interval" json:"interval"`
+
+	// The maximum amount of rotated log files to retain.
+	// A value of 0 retains all rotated log files.
+	MaxBackups uint32 `yaml:"max-backups" json:"maxBackups"`
+
+	// The maximum age of rotated log files in days.
+	// A value of 0 retains rotated log files regardless of their age.
+	MaxAge uint32 `yaml:"max-age" json:"maxAge"`
+}
+
+// Description:
+//
+//	Represents a syslog configuration.
+type ConfigLogSyslog struct {
+
+	// The network used to connect to the syslog daemon, e.g. udp or tcp.
+	// If empty, the local syslog daemon is used.
+	Network string `yaml:"network" json:"network"`
+
+	// The address of the syslog daemon, e.g. 127.0.0.1:514.
+	Address string `yaml:"address" json:"address"`
+
+	// The syslog tag.
+	Tag string `yaml:"tag" json:"tag"`
+}
+
+// Description:
+//
+//	Represents the access log configuration.
+type ConfigAccessLog struct {
+
+	// Whether to write an access log entry for every proxied request.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The access log format.
+	// Supported formats are json, combined (Apache combined log format) and template.
+	Format string `yaml:"format" json:"format"`
+
+	// The access log template, used by the template format.
+	// The template is a Go text template, e.g. {{.Method}} {{.Path}} {{.Status}}.
+	Template string `yaml:"template" json:"template,omitempty"`
+
+	// The access log output.
+	// Either stdout, stderr or a file path.
+	Output string `yaml:"output" json:"output"`
+
+	// The access log file rotation configuration.
+	// Only applies if the output is a file path.
+	Rotation ConfigLogRotation `yaml:"rotation" json:"rotation"`
 }
 
 // Description:
@@ -48,8 +291,100 @@ type ConfigReverseProxyServer struct {
 	// The allowed http methods, e.g. GET, POST, ...
 	AllowedMethods []string `yaml:"allowed-methods" json:"allowedMethods"`
 
+	// The protocol used to connect to the upstreams.
+	// Either http1, h2 (HTTP/2 over TLS) or h2c (HTTP/2 over cleartext). Defaults to http1.
+	Protocol string `yaml:"protocol" json:"protocol,omitempty"`
+
+	// The client certificate authentication configuration.
+	// Requires TLS on the listener.
+	ClientAuth ConfigReverseProxyServerClientAuth `yaml:"client-auth" json:"clientAuth"`
+
+	// The basic authentication configuration.
+	BasicAuth ConfigReverseProxyServerBasicAuth `yaml:"basic-auth" json:"basicAuth"`
+
+	// The JWT authentication configuration.
+	Jwt ConfigReverseProxyServerJwt `yaml:"jwt" json:"jwt"`
+
+	// The forward authentication configuration.
+	ForwardAuth ConfigReverseProxyServerForwardAuth `yaml:"forward-auth" json:"forwardAuth"`
+
+	// The OpenID Connect login configuration.
+	Oidc ConfigReverseProxyServerOidc `yaml:"oidc" json:"oidc"`
+
+	// The api key authentication configuration.
+	ApiKey ConfigReverseProxyServerApiKey `yaml:"api-key" json:"apiKey"`
+
+	// The client ip filter applied to requests of this server, in addition to the global filter.
+	IpFilter ConfigReverseProxyServerIpFilter `yaml:"ip-filter" json:"ipFilter"`
+
+	// The cross-origin resource sharing configuration.
+	Cors ConfigReverseProxyServerCors `yaml:"cors" json:"cors"`
+
+	// The security response headers configuration.
+	SecurityHeaders ConfigReverseProxyServerSecurityHeaders `yaml:"security-headers" json:"securityHeaders"`
+
+	// The web application firewall configuration.
+	Waf ConfigReverseProxyServerWaf `yaml:"waf" json:"waf"`
+
+	// The TLS configuration used to connect to https upstreams.
+	UpstreamTls ConfigReverseProxyServerUpstreamTls `yaml:"upstream-tls" json:"upstreamTls"`
+
+	// The connection pool configuration, applied to every upstream of the server.
+	ConnectionPool ConfigReverseProxyServerConnectionPool `yaml:"connection-pool" json:"connectionPool"`
+
+	// The load balancing strategy.
+	// Either round-robin or least-connections. Defaults to round-robin.
+	LoadBalancing string `yaml:"load-balancing" json:"loadBalancing,omitempty"`
+
 	// The health check configuration.
 	HealthCheck ConfigReverseProxyServerHealthCheck `yaml:"health-check" json:"healthCheck"`
+
+	// The websocket configuration.
+	WebSocket ConfigReverseProxyServerWebSocket `yaml:"websocket" json:"webSocket"`
+
+	// The response streaming configuration.
+	Streaming ConfigReverseProxyServerStreaming `yaml:"streaming" json:"streaming"`
+
+	// The tracing configuration.
+	Tracing ConfigReverseProxyServerTracing `yaml:"tracing" json:"tracing"`
+}
+
+// Description:
+//
+//	Represents a layer 4 stream server configuration.
+//	A stream server passes raw tcp connections or udp datagrams to its upstreams.
+type ConfigStreamServer struct {
+
+	// The name of the stream server.
+	// Must be unique across all servers and stream servers.
+	Name string `yaml:"name" json:"name"`
+
+	// The stream protocol.
+	// Either tcp or udp.
+	Protocol string `yaml:"protocol" json:"protocol"`
+
+	// The address to listen on, e.g. :5432 or 127.0.0.1:53.
+	Listen string `yaml:"listen" json:"listen"`
+
+	// The upstream addresses, e.g. 127.0.0.1:5432.
+	Upstreams []string `yaml:"upstreams" json:"upstreams"`
+
+	// The load balancing strategy.
+	// Either round-robin or least-connections. Defaults to round-robin.
+	LoadBalancing string `yaml:"load-balancing" json:"loadBalancing,omitempty"`
+
+	// The health check configuration.
+	// Tcp upstreams are checked by opening a connection, udp upstreams by sending a datagram and awaiting a reply.
+	// The endpoint is ignored. An interval of 0 disables health checks.
+	HealthCheck ConfigReverseProxyServerHealthCheck `yaml:"health-check" json:"healthCheck"`
+
+	// The maximum time in milliseconds to connect to an upstream.
+	// Defaults to 5000.
+	ConnectTimeout uint32 `yaml:"connect-timeout" json:"connectTimeout"`
+
+	// The time in milliseconds after which a connection or udp session without any traffic is closed.
+	// For tcp, a value of 0 disables the idle timeout. For udp, it defaults to 30000.
+	IdleTimeout uint32 `yaml:"idle-timeout" json:"idleTimeout"`
 }
 
 // Description:
@@ -66,6 +401,495 @@ type ConfigReverseProxyServerHealthCheck struct {
 	// The maximum amount of fails.
 	// If this amount of fails is exceeded, the upstream is considered unhealthy.
 	Fails uint32 `yaml:"fails" json:"fails"`
+
+	// The datagram sent to the upstreams of udp stream servers.
+	// Required for udp health checks, ignored otherwise.
+	Send string `yaml:"send" json:"send,omitempty"`
+
+	// The text the reply of an udp upstream has to contain.
+	// If empty, any reply is accepted. Ignored for other servers.
+	Expect string `yaml:"expect" json:"expect,omitempty"`
+}
+
+// Description:
+//
+// Represents a service client certificate authentication configuration.
+type ConfigReverseProxyServerClientAuth struct {
+
+	// Whether requests require a valid client certificate.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The path of a PEM encoded CA bundle used to verify client certificates.
+	CaFile string `yaml:"ca-file" json:"caFile"`
+
+	// The allowed certificate subjects, e.g. CN=billing,O=Example.
+	// If neither subjects nor SANs are given, any certificate signed by the CA is allowed.
+	AllowedSubjects []string `yaml:"allowed-subjects" json:"allowedSubjects,omitempty"`
+
+	// The allowed subject alternative names, i.e. DNS names, email addresses, IP addresses or URIs.
+	// A certificate is allowed, if its subject or any of its SANs is allowed.
+	AllowedSans []string `yaml:"allowed-sans" json:"allowedSans,omitempty"`
+
+	// Whether to forward the details of verified certificates to the upstreams in headers.
+	ForwardHeaders bool `yaml:"forward-headers" json:"forwardHeaders"`
+}
+
+// Description:
+//
+// Represents a service basic authentication configuration.
+type ConfigReverseProxyServerBasicAuth struct {
+
+	// Whether requests require valid basic authentication credentials.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The realm sent to clients in the authentication challenge.
+	// Defaults to revx.
+	Realm string `yaml:"realm" json:"realm"`
+
+	// The path of an htpasswd file, containing one user per line.
+	// The file is reloaded automatically, when it changes.
+	File string `yaml:"file" json:"file"`
+
+	// Inline users in htpasswd format, e.g. alice:$2y$10$...
+	// Inline users take precedence over users of the same name in the file.
+	Users []string `yaml:"users" json:"-"`
+
+	// The header used to forward the authenticated username to the upstreams.
+	// If empty, the username is not forwarded.
+	UsernameHeader string `yaml:"username-header" json:"usernameHeader"`
+}
+
+// Description:
+//
+// Represents a service JWT authentication configuration.
+// Tokens are read from the Authorization header using the Bearer scheme.
+type ConfigReverseProxyServerJwt struct {
+
+	// Whether requests require a valid token.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The accepted signature algorithms, i.e. HS256, RS256 or ES256.
+	// Defaults to all of them. Each key is only used with the algorithm matching its type.
+	Algorithms []string `yaml:"algorithms" json:"algorithms,omitempty"`
+
+	// The shared secret used to verify HS256 signatures.
+	Secret string `yaml:"secret" json:"-"`
+
+	// The path of a PEM file with RSA or EC public keys or certificates.
+	PublicKeyFile string `yaml:"public-key-file" json:"publicKeyFile"`
+
+	// The path of a JSON web key set file.
+	JwksFile string `yaml:"jwks-file" json:"jwksFile"`
+
+	// The url of a JSON web key set, e.g. https://example.com/.well-known/jwks.json.
+	JwksUrl string `yaml:"jwks-url" json:"jwksUrl"`
+
+	// The time in milliseconds a key set is cached, before it is read again.
+	// Defaults to 300000.
+	JwksCacheDuration uint32 `yaml:"jwks-cache-duration" json:"jwksCacheDuration"`
+
+	// The required issuer (iss claim). If empty, any issuer is accepted.
+	Issuer string `yaml:"issuer" json:"issuer"`
+
+	// The accepted audiences (aud claim). A token is accepted, if it contains any of them.
+	// If empty, any audience is accepted.
+	Audiences []string `yaml:"audiences" json:"audiences,omitempty"`
+
+	// The tolerated clock skew in milliseconds, when checking the exp and nbf claims.
+	Leeway uint32 `yaml:"leeway" json:"leeway"`
+
+	// Whether tokens without an exp claim are accepted.
+	// Defaults to false, i.e. tokens have to expire.
+	AllowMissingExpiration bool `yaml:"allow-missing-expiration" json:"allowMissingExpiration"`
+
+	// The claims a token must contain, mapped to their required value.
+	// An empty value only requires the claim to be present. For array claims, the array must contain the value.
+	RequiredClaims map[string]string `yaml:"required-claims" json:"requiredClaims,omitempty"`
+
+	// The claims forwarded to the upstreams, mapped to the header names.
+	ForwardClaims map[string]string `yaml:"forward-claims" json:"forwardClaims,omitempty"`
+}
+
+// Description:
+//
+// Represents a service forward authentication configuration.
+// Every request is authorized by a subrequest to an external authorization service.
+type ConfigReverseProxyServerForwardAuth struct {
+
+	// Whether requests are authorized by the authorization service.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The url of the authorization service, e.g. http://127.0.0.1:9000/verify.
+	Url string `yaml:"url" json:"url"`
+
+	// The method of the subrequest.
+	// Defaults to GET.
+	Method string `yaml:"method" json:"method"`
+
+	// The request headers copied to the subrequest.
+	// Defaults to Authorization and Cookie.
+	RequestHeaders []string `yaml:"request-headers" json:"requestHeaders,omitempty"`
+
+	// The headers of successful authorization responses copied to the upstream request, e.g. X-User-Id.
+	ResponseHeaders []string `yaml:"response-headers" json:"responseHeaders,omitempty"`
+
+	// The time in milliseconds to wait for the authorization service.
+	// Defaults to 5000.
+	Timeout uint32 `yaml:"timeout" json:"timeout"`
+
+	// The time in milliseconds an authorization decision is cached.
+	// A value of 0 disables the cache.
+	CacheTtl uint32 `yaml:"cache-ttl" json:"cacheTtl"`
+}
+
+// Description:
+//
+// Represents a service OpenID Connect login configuration.
+// Revx acts as relying party, using the authorization code flow.
+type ConfigReverseProxyServerOidc struct {
+
+	// Whether requests require a login at the identity provider.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The issuer url of the identity provider, used for discovery.
+	Issuer string `yaml:"issuer" json:"issuer"`
+
+	// The client id registered at the identity provider.
+	ClientId string `yaml:"client-id" json:"clientId"`
+
+	// The client secret registered at the identity provider.
+	ClientSecret string `yaml:"client-secret" json:"-"`
+
+	// The requested scopes.
+	// Defaults to openid, profile and email.
+	Scopes []string `yaml:"scopes" json:"scopes,omitempty"`
+
+	// The absolute url of the login callback, registered at the identity provider.
+	// Must be located within the server context. Defaults to <context>/oauth2/callback on the requested host.
+	RedirectUrl string `yaml:"redirect-url" json:"redirectUrl"`
+
+	// The path of the logout endpoint, located within the server context.
+	// Defaults to <context>/oauth2/logout.
+	LogoutPath string `yaml:"logout-path" json:"logoutPath"`
+
+	// The name of the session cookie.
+	// Defaults to revx_session.
+	CookieName string `yaml:"cookie-name" json:"cookieName"`
+
+	// The secret used to encrypt the session cookie.
+	CookieSecret string `yaml:"cookie-secret" json:"-"`
+
+	// The maximum time in milliseconds of a session, before a new login is required.
+	// Defaults to 86400000.
+	SessionDuration uint32 `yaml:"session-duration" json:"sessionDuration"`
+
+	// The claims of the id token forwarded to the upstreams, mapped to the header names.
+	ClaimHeaders map[string]string `yaml:"claim-headers" json:"claimHeaders,omitempty"`
+
+	// Whether to forward the access token to the upstreams in the Authorization header.
+	ForwardAccessToken bool `yaml:"forward-access-token" json:"forwardAccessToken"`
+}
+
+// Description:
+//
+// Represents a service api key authentication configuration.
+// The keys, their rate limits and daily quotas are read from a key file, which can be shared by several servers.
+type ConfigReverseProxyServerApiKey struct {
+
+	// Whether requests require a valid api key.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The path of the key file.
+	File string `yaml:"file" json:"file"`
+
+	// The request header carrying the api key.
+	// Defaults to X-Api-Key.
+	Header string `yaml:"header" json:"header"`
+
+	// The query parameter carrying the api key, if keys may also be passed in the query.
+	QueryParameter string `yaml:"query-parameter" json:"queryParameter"`
+
+	// The request header used to pass the name of the key to the upstreams.
+	NameHeader string `yaml:"name-header" json:"nameHeader"`
+}
+
+// Description:
+//
+// Represents a service client ip filter configuration.
+type ConfigReverseProxyServerIpFilter struct {
+
+	// The addresses or CIDR ranges allowed to send requests to this server.
+	// If empty, all clients not denied are allowed.
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+
+	// The addresses or CIDR ranges denied to send requests to this server.
+	// Deny rules take precedence over allow rules.
+	Deny []string `yaml:"deny" json:"deny,omitempty"`
+
+	// The response status of rejected requests.
+	// Defaults to the status of the global filter.
+	Status int `yaml:"status" json:"status"`
+}
+
+// Description:
+//
+// Represents a service cross-origin resource sharing (CORS) configuration.
+// Preflight requests are answered by revx, without passing them to the upstreams.
+type ConfigReverseProxyServerCors struct {
+
+	// Whether revx handles cross-origin requests for this server.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The allowed origins.
+	// Either an exact origin, a wildcard pattern like https://*.example.com, a regular expression prefixed by ~, or * for any origin.
+	AllowedOrigins []string `yaml:"allowed-origins" json:"allowedOrigins,omitempty"`
+
+	// The methods allowed in cross-origin requests.
+	// Defaults to the allowed methods of the server.
+	AllowedMethods []string `yaml:"allowed-methods" json:"allowedMethods,omitempty"`
+
+	// The request headers allowed in cross-origin requests, or * for any header.
+	// Defaults to Accept, Content-Type and X-Requested-With.
+	AllowedHeaders []string `yaml:"allowed-headers" json:"allowedHeaders,omitempty"`
+
+	// The response headers exposed to scripts.
+	ExposedHeaders []string `yaml:"exposed-headers" json:"exposedHeaders,omitempty"`
+
+	// Whether cross-origin requests may include credentials, e.g. cookies.
+	AllowCredentials bool `yaml:"allow-credentials" json:"allowCredentials"`
+
+	// The time in milliseconds browsers may cache preflight responses.
+	// If 0, the browser default is used.
+	MaxAge uint32 `yaml:"max-age" json:"maxAge"`
+}
+
+// Description:
+//
+// Represents a service security response headers configuration.
+// Every header defaults to the value of the preset. An empty value removes the header from the preset.
+type ConfigReverseProxyServerSecurityHeaders struct {
+
+	// Whether security headers are added to responses.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// The preset providing the default header values.
+	// Either strict, relaxed or empty for no defaults.
+	Preset string `yaml:"preset" json:"preset"`
+
+	// Whether to replace security headers set by the upstreams.
+	// If not set, headers of the upstreams are kept.
+	Override bool `yaml:"override" json:"override"`
+
+	// The Strict-Transport-Security header.
+	StrictTransportSecurity *string `yaml:"strict-transport-security" json:"strictTransportSecurity,omitempty"`
+
+	// The Content-Security-Policy header.
+	ContentSecurityPolicy *string `yaml:"content-security-policy" json:"contentSecurityPolicy,omitempty"`
+
+	// The X-Frame-Options header.
+	FrameOptions *string `yaml:"frame-options" json:"frameOptions,omitempty"`
+
+	// The X-Content-Type-Options header.
+	ContentTypeOptions *string `yaml:"content-type-options" json:"contentTypeOptions,omitempty"`
+
+	// The Referrer-Policy header.
+	ReferrerPolicy *string `yaml:"referrer-policy" json:"referrerPolicy,omitempty"`
+
+	// The Permissions-Policy header.
+	PermissionsPolicy *string `yaml:"permissions-policy" json:"permissionsPolicy,omitempty"`
+
+	// Whether to remove response headers of the upstreams, which reveal server software and versions, e.g. Server and X-Powered-By.
+	StripServerHeaders bool `yaml:"strip-server-headers" json:"stripServerHeaders"`
+}
+
+// Description:
+//
+// Represents a service web application firewall configuration.
+// Requests are inspected by a list of rules, before they are passed to the upstreams.
+type ConfigReverseProxyServerWaf struct {
+
+	// Whether requests are inspected.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Whether to include the default ruleset, covering sql injection, path traversal and scanner user agents.
+	DefaultRules bool `yaml:"default-rules" json:"defaultRules"`
+
+	// The action of the default rules.
+	// Either block or log. Defaults to block.
+	DefaultRulesAction string `yaml:"default-rules-action" json:"defaultRulesAction"`
+
+	// The ids of default rules not to apply.
+	DisabledRules []string `yaml:"disabled-rules" json:"disabledRules,omitempty"`
+
+	// The custom rules, applied after the default rules.
+	Rules []ConfigWafRule `yaml:"rules" json:"rules,omitempty"`
+
+	// The response status of blocked requests.
+	// Defaults to 403.
+	Status int `yaml:"status" json:"status"`
+
+	// The maximum amount of request body bytes inspected.
+	// Defaults to 65536.
+	MaxBodySize uint32 `yaml:"max-body-size" json:"maxBodySize"`
+
+	// The request header used to pass the tags of matching tag rules to the upstreams.
+	// Defaults to X-Waf-Tags.
+	TagHeader string `yaml:"tag-header" json:"tagHeader"`
+}
+
+// Description:
+//
+// Represents a web application firewall rule.
+// A rule matches, if all of its conditions match.
+type ConfigWafRule struct {
+
+	// The unique id of the rule, used in logs and statistics.
+	Id string `yaml:"id" json:"id"`
+
+	// The action taken, if the rule matches.
+	// Either block, log or tag.
+	Action string `yaml:"action" json:"action"`
+
+	// The tag added to matching requests by tag rules.
+	// Defaults to the rule id.
+	Tag string `yaml:"tag" json:"tag,omitempty"`
+
+	// The conditions of the rule.
+	Conditions []ConfigWafCondition `yaml:"conditions" json:"conditions"`
+}
+
+// Description:
+//
+// Represents a condition of a web application firewall rule.
+// A condition matches, if any of its targets matches.
+type ConfigWafCondition struct {
+
+	// The inspected parts of the request.
+	// Either method, path, query, query:<parameter>, header:<name>, headers or body.
+	Targets []string `yaml:"targets" json:"targets"`
+
+	// The operator.
+	// Either regex, contains, equals or size.
+	Operator string `yaml:"operator" json:"operator"`
+
+	// The operand, i.e. the regular expression, the text or the maximum size in bytes.
+	Value string `yaml:"value" json:"value"`
+}
+
+// Description:
+//
+// Represents a service upstream TLS configuration.
+type ConfigReverseProxyServerUpstreamTls struct {
+
+	// The path of a PEM encoded CA bundle used to verify upstream certificates.
+	// If empty, the system CAs are used.
+	CaFile string `yaml:"ca-file" json:"caFile,omitempty"`
+
+	// The path of a PEM encoded client certificate presented to the upstreams.
+	CertFile string `yaml:"cert-file" json:"certFile,omitempty"`
+
+	// The path of the PEM encoded private key of the client certificate.
+	KeyFile string `yaml:"key-file" json:"keyFile,omitempty"`
+
+	// The server name sent with SNI and used to verify upstream certificates.
+	// If empty, the upstream host is used.
+	ServerName string `yaml:"server-name" json:"serverName,omitempty"`
+
+	// The minimum TLS version.
+	// Either 1.2 or 1.3. Defaults to 1.2.
+	MinVersion string `yaml:"min-version" json:"minVersion,omitempty"`
+
+	// Whether to skip the verification of upstream certificates.
+	// Must only be used for development.
+	InsecureSkipVerify bool `yaml:"insecure-skip-verify" json:"insecureSkipVerify"`
+}
+
+// Description:
+//
+// Represents a service websocket configuration.
+type ConfigReverseProxyServerWebSocket struct {
+
+	// Whether websocket upgrades are passed to the upstreams.
+	// If not set, websockets are enabled.
+	Enabled *bool `yaml:"enabled" json:"enabled,omitempty"`
+
+	// The time in milliseconds after which a websocket without any traffic is closed.
+	// A value of 0 disables the idle timeout.
+	IdleTimeout uint32 `yaml:"idle-timeout" json:"idleTimeout"`
+
+	// The maximum size in bytes of a single message sent by a client.
+	// A value of 0 disables the limit.
+	MaxMessageSize uint32 `yaml:"max-message-size" json:"maxMessageSize"`
+
+	// The maximum amount of concurrent websockets per upstream.
+	// A value of 0 disables the limit.
+	MaxConnections uint32 `yaml:"max-connections" json:"maxConnections"`
+}
+
+// Description:
+//
+// Represents a service upstream connection pool configuration.
+// Every upstream owns its own pool. A value of 0 selects the default.
+type ConfigReverseProxyServerConnectionPool struct {
+
+	// The maximum amount of idle connections kept open per upstream.
+	// Defaults to 32. Only supported by HTTP/1 upstreams.
+	MaxIdleConnections uint32 `yaml:"max-idle-connections" json:"maxIdleConnections"`
+
+	// The maximum amount of connections per upstream, including active and idle connections.
+	// Requests wait for a free connection once the limit is reached. Defaults to no limit.
+	// HTTP/2 upstreams use a single connection with a limit, requests wait for a free stream.
+	MaxConnections uint32 `yaml:"max-connections" json:"maxConnections"`
+
+	// The time in milliseconds after which an idle connection is closed.
+	// Defaults to 90000.
+	IdleTimeout uint32 `yaml:"idle-timeout" json:"idleTimeout"`
+
+	// The time in milliseconds to wait for a connection to be established.
+	// Defaults to 30000.
+	ConnectTimeout uint32 `yaml:"connect-timeout" json:"connectTimeout"`
+
+	// Whether connections are reused for subsequent requests.
+	// If not set, connections are reused. HTTP/2 upstreams always reuse connections.
+	KeepAlive *bool `yaml:"keep-alive" json:"keepAlive,omitempty"`
+
+	// The interval in milliseconds of tcp keep-alive probes on open connections.
+	// Defaults to 30000.
+	KeepAliveInterval uint32 `yaml:"keep-alive-interval" json:"keepAliveInterval"`
+
+	// The size in bytes of the read buffer of a connection.
+	// Defaults to 4096. Only supported by HTTP/1 upstreams.
+	ReadBufferSize uint32 `yaml:"read-buffer-size" json:"readBufferSize"`
+
+	// The size in bytes of the write buffer of a connection.
+	// Defaults to 4096. Only supported by HTTP/1 upstreams.
+	WriteBufferSize uint32 `yaml:"write-buffer-size" json:"writeBufferSize"`
+}
+
+// Description:
+//
+// Represents a service response streaming configuration.
+type ConfigReverseProxyServerStreaming struct {
+
+	// The interval in milliseconds in which response data is flushed to the client.
+	// A value of -1 flushes after every write, a value of 0 only flushes once the response is complete.
+	// Streaming responses, e.g. text/event-stream, are always flushed after every write.
+	FlushInterval int32 `yaml:"flush-interval" json:"flushInterval"`
+
+	// Whether response data may be buffered.
+	// If disabled, all responses are flushed after every write, regardless of the flush interval.
+	// If not set, buffering is enabled.
+	Buffering *bool `yaml:"buffering" json:"buffering,omitempty"`
+}
+
+// Description:
+//
+// Represents a service tracing configuration.
+type ConfigReverseProxyServerTracing struct {
+
+	// The sample rate between 0 and 1 for new traces of this server.
+	// If not set, the global sample rate is used.
+	SampleRate *float64 `yaml:"sample-rate" json:"sampleRate,omitempty"`
 }
 
 // The global configuration.
@@ -84,6 +908,41 @@ var mutex = sync.Mutex{}
 func Default() *ConfigRevx {
 	return &ConfigRevx{
 		Port: DefaultPort,
+		Http2: ConfigHttp2{
+			Enabled: true,
+			H2c:     false,
+		},
+		AccessLog: ConfigAccessLog{
+			Enabled: true,
+			Format:  "combined",
+			Output:  "stdout",
+		},
+		Log: ConfigLog{
+			Level:  "info",
+			Output: "stdout",
+			Syslog: ConfigLogSyslog{
+				Tag: "revx",
+			},
+		},
+		Tracing: ConfigTracing{
+			Endpoint:      "http://localhost:4318/v1/traces",
+			ServiceName:   "revx",
+			SampleRate:    1,
+			BatchSize:     512,
+			FlushInterval: 5000,
+		},
+		RequestId: ConfigRequestId{
+			Enabled:       true,
+			Header:        "X-Request-Id",
+			TrustIncoming: true,
+		},
+		Shutdown: ConfigShutdown{
+			Delay:        0,
+			DrainTimeout: 30000,
+		},
+		Upgrade: ConfigUpgrade{
+			ReadyTimeout: 30000,
+		},
 	}
 }
 
@@ -142,6 +1001,7 @@ func readConfigFile(path string) ([]byte, error) {
 // Description:
 //
 //	Unmarshals the configuration file content, given in bytes.
+//	Values missing in the configuration file keep their defaults.
 //
 // Parameters:
 //
@@ -151,12 +1011,12 @@ func readConfigFile(path string) ([]byte, error) {
 //
 //	The reverse proxy configuration object.
 func unmarshalConfig(file []byte) (*ConfigRevx, error) {
-	config := ConfigRevx{}
-	err := yaml.Unmarshal(file, &config)
+	config := Default()
+	err := yaml.Unmarshal(file, config)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return config, nil
 }
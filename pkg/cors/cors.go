@@ -0,0 +1,333 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The cors subsystem logger.
+var log = logging.NewLogger("cors")
+
+// The request headers allowed, if none are configured.
+var defaultAllowedHeaders = []string{"Accept", "Content-Type", "X-Requested-With"}
+
+// Description:
+//
+//	A cross-origin resource sharing policy.
+type policy struct {
+	anyOrigin      bool
+	origins        map[string]bool
+	patterns       []*regexp.Regexp
+	methods        map[string]bool
+	allowedMethods string
+	anyHeader      bool
+	headers        map[string]bool
+	allowedHeaders string
+	exposedHeaders string
+	credentials    bool
+	maxAge         string
+	options        bool
+}
+
+// Description:
+//
+//	Creates a middleware, which handles cross-origin requests.
+//	Preflight requests are answered directly. For other requests, the CORS response headers
+//	of the upstreams are replaced by the headers of the policy.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if an origin pattern is invalid.
+func Middleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	if !conf.Cors.Enabled {
+		return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+			return handler
+		}, nil
+	}
+
+	policy, err := newPolicy(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			origin := request.Header.Get("Origin")
+			preflight := request.Method == http.MethodOptions && origin != "" && request.Header.Get("Access-Control-Request-Method") != ""
+
+			if preflight {
+				policy.preflight(conf.Name, request, response)
+				return
+			}
+
+			if request.Method == http.MethodOptions && !policy.options {
+				proxy.WriteError(response, request, http.StatusMethodNotAllowed, "Method not allowed.")
+				return
+			}
+
+			allowed := origin != "" && policy.allowsOrigin(origin)
+
+			response = router.NewHeaderInterceptor(response, func(header http.Header, statusCode int) {
+				removeCorsHeaders(header)
+				header.Add("Vary", "Origin")
+
+				if allowed {
+					policy.writeOrigin(header, origin)
+
+					if policy.exposedHeaders != "" {
+						header.Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+					}
+				}
+			})
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates the policy of a server.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The policy, or an error, if an origin pattern is invalid.
+func newPolicy(conf config.ConfigReverseProxyServer) (*policy, error) {
+	cors := conf.Cors
+
+	policy := &policy{
+		origins:        map[string]bool{},
+		methods:        map[string]bool{},
+		headers:        map[string]bool{},
+		exposedHeaders: strings.Join(cors.ExposedHeaders, ", "),
+		credentials:    cors.AllowCredentials,
+	}
+
+	for _, origin := range cors.AllowedOrigins {
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.HasPrefix(origin, "~"):
+			pattern, err := regexp.Compile(origin[1:])
+
+			if err != nil {
+				return nil, fmt.Errorf("cors: invalid origin pattern: %s: %s", origin, err)
+			}
+
+			policy.patterns = append(policy.patterns, pattern)
+		case strings.Contains(origin, "*"):
+			expression := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[^/]*`)
+			policy.patterns = append(policy.patterns, regexp.MustCompile("^"+expression+"$"))
+		default:
+			policy.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	if policy.anyOrigin && policy.credentials {
+		return nil, fmt.Errorf("cors: any origin cannot be combined with allow-credentials")
+	}
+
+	methods := cors.AllowedMethods
+
+	if len(methods) == 0 {
+		methods = conf.AllowedMethods
+	}
+
+	names := []string{}
+
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+
+		if !policy.methods[method] {
+			policy.methods[method] = true
+			names = append(names, method)
+		}
+	}
+
+	policy.allowedMethods = strings.Join(names, ", ")
+
+	headers := cors.AllowedHeaders
+
+	if len(headers) == 0 {
+		headers = defaultAllowedHeaders
+	}
+
+	for _, header := range headers {
+		if header == "*" {
+			policy.anyHeader = true
+		}
+
+		policy.headers[strings.ToLower(header)] = true
+	}
+
+	policy.allowedHeaders = strings.Join(headers, ", ")
+
+	if cors.MaxAge > 0 {
+		policy.maxAge = strconv.FormatUint(uint64(cors.MaxAge)/1000, 10)
+	}
+
+	for _, method := range conf.AllowedMethods {
+		if strings.EqualFold(method, http.MethodOptions) {
+			policy.options = true
+		}
+	}
+
+	return policy, nil
+}
+
+// Description:
+//
+//	Answers a preflight request.
+//	Disallowed preflight requests are rejected with 403.
+//
+// Parameters:
+//
+//	name 		The server name.
+//	request 	The preflight request.
+//	response 	The response writer.
+func (policy *policy) preflight(name string, request *http.Request, response http.ResponseWriter) {
+	origin := request.Header.Get("Origin")
+	method := request.Header.Get("Access-Control-Request-Method")
+	headers := request.Header.Values("Access-Control-Request-Headers")
+
+	header := response.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	if !policy.allowsOrigin(origin) {
+		log.Debugf("cors: origin not allowed: %s: %s", name, origin)
+		proxy.WriteError(response, request, http.StatusForbidden, "Origin not allowed.")
+
+		return
+	}
+
+	if !policy.methods[strings.ToUpper(method)] {
+		log.Debugf("cors: method not allowed: %s: %s", name, method)
+		proxy.WriteError(response, request, http.StatusForbidden, "Method not allowed.")
+
+		return
+	}
+
+	requested := []string{}
+
+	for _, value := range headers {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+
+			if field == "" {
+				continue
+			}
+
+			if !policy.anyHeader && !policy.headers[strings.ToLower(field)] {
+				log.Debugf("cors: header not allowed: %s: %s", name, field)
+				proxy.WriteError(response, request, http.StatusForbidden, "Header not allowed.")
+
+				return
+			}
+
+			requested = append(requested, field)
+		}
+	}
+
+	policy.writeOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", policy.allowedMethods)
+
+	if policy.anyHeader {
+		// The wildcard is not supported for requests with credentials, so the requested headers are echoed.
+		if len(requested) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+	} else {
+		header.Set("Access-Control-Allow-Headers", policy.allowedHeaders)
+	}
+
+	if policy.maxAge != "" {
+		header.Set("Access-Control-Max-Age", policy.maxAge)
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// Description:
+//
+//	Checks whether an origin is allowed.
+//
+// Parameters:
+//
+//	origin The origin.
+//
+// Returns:
+//
+//	True, if the origin is allowed.
+func (policy *policy) allowsOrigin(origin string) bool {
+	if policy.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+
+	if policy.origins[origin] {
+		return true
+	}
+
+	for _, pattern := range policy.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Sets the allowed origin and credentials headers.
+//
+// Parameters:
+//
+//	header 	The response header.
+//	origin 	The allowed origin.
+func (policy *policy) writeOrigin(header http.Header, origin string) {
+	if policy.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if policy.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Description:
+//
+//	Removes all CORS response headers, e.g. those set by an upstream.
+//
+// Parameters:
+//
+//	header The response header.
+func removeCorsHeaders(header http.Header) {
+	for name := range header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			header.Del(name)
+		}
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/revx-official/output/log"
+	"github.com/revx-official/revx/pkg/logging"
 )
 
+// The env subsystem logger.
+var log = logging.NewLogger("env")
+
 // Description:
 //
 //	Gets the value of an environment variable, if it exists.
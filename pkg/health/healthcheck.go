@@ -1,13 +1,18 @@
 package health
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/revx-official/output/log"
 	"github.com/revx-official/revx/pkg/proxy"
 )
 
+// The maximum size of an udp health check reply.
+const udpMaxReplySize = 64 * 1024
+
 type HealthCheckRoutine struct {
 	Proxy  *proxy.ReverseProxyServerInfo
 	Ticker *time.Ticker
@@ -31,6 +36,11 @@ func RunHealthCheckRoutine(healthCheck *HealthCheckRoutine) {
 	go internalRunHealthCheckRoutine(healthCheck)
 }
 
+func StopHealthCheckRoutine(healthCheck *HealthCheckRoutine) {
+	healthCheck.Ticker.Stop()
+	close(healthCheck.Cancel)
+}
+
 func internalRunHealthCheckRoutine(healthCheck *HealthCheckRoutine) {
 	for {
 		select {
@@ -44,10 +54,10 @@ func internalRunHealthCheckRoutine(healthCheck *HealthCheckRoutine) {
 
 func healthCheckRoutineInterval(healthCheck *HealthCheckRoutine, timeStamp time.Time) {
 	for index := range healthCheck.Proxy.Upstreams {
-		instanceRef := &healthCheck.Proxy.Upstreams[index]
+		instanceRef := healthCheck.Proxy.Upstreams[index]
 
-		log.Tracef("health: running check: get %s %s", instanceRef.TargetUrl.String(), timeStamp)
-		response, err := http.Get(instanceRef.TargetUrl.String())
+		log.Tracef("health: running check: %s %s", instanceRef.TargetUrl.String(), timeStamp)
+		err := checkUpstream(healthCheck.Proxy, instanceRef)
 
 		if err != nil {
 			instanceRef.HealthStats.Error = err.Error()
@@ -63,12 +73,83 @@ func healthCheckRoutineInterval(healthCheck *HealthCheckRoutine, timeStamp time.
 			continue
 		}
 
-		defer response.Body.Close()
-
 		instanceRef.HealthStats.Healthy = true
 		instanceRef.HealthStats.ConsecutiveFails = 0
 		instanceRef.HealthStats.Error = ""
 
-		log.Tracef("health: check succeeded: get %s, status: %d", instanceRef.TargetUrl.String(), response.StatusCode)
+		log.Tracef("health: check succeeded: %s", instanceRef.TargetUrl.String())
+	}
+}
+
+func checkUpstream(prox *proxy.ReverseProxyServerInfo, instance *proxy.ReverseProxyServerUpstreamInfo) error {
+	switch prox.Protocol {
+	case proxy.ProtocolUdp:
+		timeout := time.Duration(prox.HealthCheckInfo.Interval) * time.Millisecond
+		return checkUdpUpstream(instance, prox.HealthCheckInfo.Send, prox.HealthCheckInfo.Expect, timeout)
+	case proxy.ProtocolTcp:
+		timeout := time.Duration(prox.HealthCheckInfo.Interval) * time.Millisecond
+		return checkTcpUpstream(instance, timeout)
+	}
+
+	return checkHttpUpstream(instance)
+}
+
+func checkHttpUpstream(instance *proxy.ReverseProxyServerUpstreamInfo) error {
+	client := http.Client{Transport: instance.Transport}
+	response, err := client.Get(instance.RequestUrl.String())
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	log.Tracef("health: check response: get %s, status: %d", instance.TargetUrl.String(), response.StatusCode)
+	return nil
+}
+
+func checkTcpUpstream(instance *proxy.ReverseProxyServerUpstreamInfo, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", instance.TargetUrl.Host, timeout)
+
+	if err != nil {
+		return err
 	}
+
+	return conn.Close()
+}
+
+func checkUdpUpstream(instance *proxy.ReverseProxyServerUpstreamInfo, send string, expect string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("udp", instance.TargetUrl.Host, timeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(timeout))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write([]byte(send))
+
+	if err != nil {
+		return err
+	}
+
+	// An unreachable port is reported by an icmp error on the read, a silent upstream by the deadline.
+	reply := make([]byte, udpMaxReplySize)
+	size, err := conn.Read(reply)
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(string(reply[:size]), expect) {
+		return fmt.Errorf("unexpected reply of %d bytes", size)
+	}
+
+	return nil
 }
@@ -3,9 +3,12 @@ package health
 import (
 	"sync"
 
-	"github.com/revx-official/output/log"
+	"github.com/revx-official/revx/pkg/logging"
 )
 
+// The health subsystem logger.
+var log = logging.NewLogger("health")
+
 type HeathCheckManager struct {
 	Routines map[string]*HealthCheckRoutine
 }
@@ -30,3 +33,13 @@ func RegisterHealthCheckRoutine(healthCheck *HealthCheckRoutine) {
 
 	Manager.Routines[name] = healthCheck
 }
+
+func StopHealthCheckRoutines() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for name, healthCheck := range Manager.Routines {
+		StopHealthCheckRoutine(healthCheck)
+		delete(Manager.Routines, name)
+	}
+}
@@ -0,0 +1,481 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The ipfilter subsystem logger.
+var log = logging.NewLogger("ipfilter")
+
+// The response status of rejected requests, if none is configured.
+const defaultStatus int = http.StatusForbidden
+
+// The addresses allowed to access the revx api, if none are configured.
+var defaultAdminAllow = []string{"127.0.0.0/8", "::1/128"}
+
+// The filter resolving client addresses by the global trusted proxies.
+var resolver = struct {
+	once   sync.Once
+	filter *Filter
+}{}
+
+// Description:
+//
+//	A list of allow and deny rules.
+type Rules struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// Description:
+//
+//	Decides whether clients may send requests, based on their ip address.
+//	A client has to pass all rules, e.g. the global rules and the rules of a server.
+type Filter struct {
+	trusted []*net.IPNet
+	rules   []Rules
+	status  int
+}
+
+// Description:
+//
+//	Creates a middleware, which rejects requests of clients not passing the global and the server filter.
+//	If neither filter has rules, requests are passed on unchanged.
+//
+// Parameters:
+//
+//	global The global filter configuration.
+//	conf   The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if a rule or the status is invalid.
+func Middleware(global config.ConfigIpFilter, conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	filter, err := NewFilter(global, conf.IpFilter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		if filter.Empty() {
+			return handler
+		}
+
+		return func(request *http.Request, response http.ResponseWriter) {
+			ip, allowed := filter.Check(request.RemoteAddr, strings.Join(request.Header.Values("X-Forwarded-For"), ","))
+
+			if !allowed {
+				log.Debugf("ipfilter: rejected client: %s: %s%s", conf.Name, ip, proxy.RequestIdSuffix(request))
+				proxy.WriteError(response, request, filter.status, http.StatusText(filter.status)+".")
+
+				return
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates the filter of a server.
+//	Requests have to pass both the global and the server rules.
+//
+// Parameters:
+//
+//	global The global filter configuration.
+//	server The server filter configuration.
+//
+// Returns:
+//
+//	The filter, or an error, if a rule or the status is invalid.
+func NewFilter(global config.ConfigIpFilter, server config.ConfigReverseProxyServerIpFilter) (*Filter, error) {
+	filter, err := NewGlobalFilter(global)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := ParseRules(server.Allow, server.Deny)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter.rules = append(filter.rules, rules)
+
+	if server.Status != 0 {
+		filter.status, err = parseStatus(server.Status)
+	}
+
+	return filter, err
+}
+
+// Description:
+//
+//	Creates the filter of the revx api.
+//	Requests have to pass the global rules and the admin allow rules, which default to localhost.
+//
+// Parameters:
+//
+//	global The global filter configuration.
+//
+// Returns:
+//
+//	The filter, or an error, if a rule or the status is invalid.
+func NewAdminFilter(global config.ConfigIpFilter) (*Filter, error) {
+	filter, err := NewGlobalFilter(global)
+
+	if err != nil {
+		return nil, err
+	}
+
+	allow := global.AdminAllow
+
+	if len(allow) == 0 {
+		allow = defaultAdminAllow
+	}
+
+	rules, err := ParseRules(allow, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter.rules = append(filter.rules, rules)
+	return filter, nil
+}
+
+// Description:
+//
+//	Creates a filter with the global rules.
+//	Used by stream servers, which are only subject to the global rules.
+//
+// Parameters:
+//
+//	global The global filter configuration.
+//
+// Returns:
+//
+//	The filter, or an error, if a rule or the status is invalid.
+func NewGlobalFilter(global config.ConfigIpFilter) (*Filter, error) {
+	trusted, err := parseNetworks(global.TrustedProxies)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := ParseRules(global.Allow, global.Deny)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &Filter{
+		trusted: trusted,
+		rules:   []Rules{rules},
+		status:  defaultStatus,
+	}
+
+	if global.Status != 0 {
+		filter.status, err = parseStatus(global.Status)
+	}
+
+	return filter, err
+}
+
+// Description:
+//
+//	Checks whether a client may send a request.
+//
+// Parameters:
+//
+//	remoteAddr 		The address of the connection peer.
+//	forwardedFor 	The comma separated X-Forwarded-For header values.
+//
+// Returns:
+//
+//	The client ip address, and whether the client passes all rules.
+func (filter *Filter) Check(remoteAddr string, forwardedFor string) (net.IP, bool) {
+	ip := filter.ClientIp(remoteAddr, forwardedFor)
+
+	for _, rules := range filter.rules {
+		if !rules.Allows(ip) {
+			return ip, false
+		}
+	}
+
+	return ip, true
+}
+
+// Description:
+//
+//	Determines the ip address of the client.
+//	If the connection peer is a trusted proxy, the X-Forwarded-For header is evaluated from right to left,
+//	and the first address, which is not a trusted proxy, is the client.
+//
+// Parameters:
+//
+//	remoteAddr 		The address of the connection peer.
+//	forwardedFor 	The comma separated X-Forwarded-For header values.
+//
+// Returns:
+//
+//	The client ip address, or nil, if it cannot be determined.
+func (filter *Filter) ClientIp(remoteAddr string, forwardedFor string) net.IP {
+	ip := parseIp(remoteAddr)
+
+	if ip == nil || forwardedFor == "" || !containsIp(filter.trusted, ip) {
+		return ip
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+
+	for index := len(hops) - 1; index >= 0; index-- {
+		ip = parseIp(strings.TrimSpace(hops[index]))
+
+		if ip == nil || !containsIp(filter.trusted, ip) {
+			return ip
+		}
+	}
+
+	return ip
+}
+
+// Description:
+//
+//	Determines the ip address of the client of a request, like the ip filter does.
+//	Uses the global trusted proxies, so logs and subrequests name the client, which was allowed or denied.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The client ip address, or the connection peer, if the client cannot be determined.
+func RequestClientIp(request *http.Request) string {
+	resolver.once.Do(func() {
+		filter, err := NewGlobalFilter(config.Global.IpFilter)
+
+		if err != nil {
+			// Invalid trusted proxies are reported, once the ip filters are created.
+			filter = &Filter{}
+		}
+
+		resolver.filter = filter
+	})
+
+	ip := resolver.filter.ClientIp(request.RemoteAddr, strings.Join(request.Header.Values("X-Forwarded-For"), ","))
+
+	if ip != nil {
+		return ip.String()
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return host
+}
+
+// Description:
+//
+//	Gets the response status of rejected requests.
+//
+// Returns:
+//
+//	The response status.
+func (filter *Filter) Status() int {
+	return filter.status
+}
+
+// Description:
+//
+//	Checks whether the filter has no rules, i.e. allows every client.
+//
+// Returns:
+//
+//	True, if the filter has no rules.
+func (filter *Filter) Empty() bool {
+	for _, rules := range filter.rules {
+		if len(rules.allow) > 0 || len(rules.deny) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Description:
+//
+//	Parses allow and deny rules.
+//
+// Parameters:
+//
+//	allow 	The allowed addresses or CIDR ranges.
+//	deny 	The denied addresses or CIDR ranges.
+//
+// Returns:
+//
+//	The rules, or an error, if a rule is invalid.
+func ParseRules(allow []string, deny []string) (Rules, error) {
+	allowed, err := parseNetworks(allow)
+
+	if err != nil {
+		return Rules{}, err
+	}
+
+	denied, err := parseNetworks(deny)
+
+	if err != nil {
+		return Rules{}, err
+	}
+
+	return Rules{allow: allowed, deny: denied}, nil
+}
+
+// Description:
+//
+//	Checks whether the rules allow a client.
+//	Denied clients are rejected. If there are allow rules, only allowed clients pass.
+//	Unknown clients only pass, if there are no allow rules.
+//
+// Parameters:
+//
+//	ip The client ip address, or nil, if unknown.
+//
+// Returns:
+//
+//	True, if the client passes.
+func (rules Rules) Allows(ip net.IP) bool {
+	if ip == nil {
+		return len(rules.allow) == 0
+	}
+
+	if containsIp(rules.deny, ip) {
+		return false
+	}
+
+	return len(rules.allow) == 0 || containsIp(rules.allow, ip)
+}
+
+// Description:
+//
+//	Parses a list of addresses or CIDR ranges.
+//	Single addresses are converted to ranges containing only the address.
+//
+// Parameters:
+//
+//	entries The addresses or CIDR ranges.
+//
+// Returns:
+//
+//	The ranges, or an error, if an entry is invalid.
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+
+			if ip == nil {
+				return nil, fmt.Errorf("ipfilter: invalid address: %s", entry)
+			}
+
+			bits := 8 * net.IPv6len
+
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+
+		if err != nil {
+			return nil, fmt.Errorf("ipfilter: invalid CIDR range: %s", entry)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// Description:
+//
+//	Validates the response status of rejected requests.
+//
+// Parameters:
+//
+//	status The configured status.
+//
+// Returns:
+//
+//	The status, or an error, if it is not a 4xx or 5xx status.
+func parseStatus(status int) (int, error) {
+	if status < 400 || status > 599 {
+		return 0, fmt.Errorf("ipfilter: invalid status: %d", status)
+	}
+
+	return status, nil
+}
+
+// Description:
+//
+//	Parses an ip address, optionally followed by a port.
+//
+// Parameters:
+//
+//	address The address.
+//
+// Returns:
+//
+//	The ip address, or nil, if the address is invalid.
+func parseIp(address string) net.IP {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+
+	return net.ParseIP(address)
+}
+
+// Description:
+//
+//	Checks whether any of the ranges contains an ip address.
+//
+// Parameters:
+//
+//	networks 	The ranges.
+//	ip 			The ip address.
+//
+// Returns:
+//
+//	True, if a range contains the address.
+func containsIp(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
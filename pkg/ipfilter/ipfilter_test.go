@@ -0,0 +1,153 @@
+package ipfilter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+func TestFilterClientIp(t *testing.T) {
+	filter, err := NewGlobalFilter(config.ConfigIpFilter{
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		ip           string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.5:4711", ip: "203.0.113.5"},
+		{name: "direct client without port", remoteAddr: "203.0.113.5", ip: "203.0.113.5"},
+		{name: "untrusted peer ignores header", remoteAddr: "203.0.113.5:4711", forwardedFor: "198.51.100.1", ip: "203.0.113.5"},
+		{name: "trusted peer without header", remoteAddr: "10.0.0.1:4711", ip: "10.0.0.1"},
+		{name: "trusted peer", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.100.1", ip: "198.51.100.1"},
+		{name: "trusted chain", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.100.1, 192.168.1.1, 10.1.2.3", ip: "198.51.100.1"},
+		{name: "spoofed leftmost entry", remoteAddr: "10.0.0.1:4711", forwardedFor: "1.2.3.4, 198.51.100.1, 10.1.2.3", ip: "198.51.100.1"},
+		{name: "untrusted proxy in chain", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.100.1, 192.168.1.2", ip: "192.168.1.2"},
+		{name: "only trusted hops", remoteAddr: "10.0.0.1:4711", forwardedFor: "10.0.0.2, 10.0.0.3", ip: "10.0.0.2"},
+		{name: "invalid hop", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.100.1, garbage", ip: ""},
+		{name: "hop with port", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.100.1:1234", ip: "198.51.100.1"},
+		{name: "ipv6 trusted peer", remoteAddr: "[fd00::1]:4711", forwardedFor: "2001:db8::1", ip: "2001:db8::1"},
+		{name: "invalid peer", remoteAddr: "garbage", forwardedFor: "198.51.100.1", ip: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ip := filter.ClientIp(test.remoteAddr, test.forwardedFor)
+
+			if test.ip == "" {
+				if ip != nil {
+					t.Fatalf("expected no ip, got %s", ip)
+				}
+
+				return
+			}
+
+			if !ip.Equal(net.ParseIP(test.ip)) {
+				t.Fatalf("expected %s, got %s", test.ip, ip)
+			}
+		})
+	}
+}
+
+func TestRulesAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		ip      string
+		allowed bool
+	}{
+		{name: "no rules", ip: "203.0.113.5", allowed: true},
+		{name: "no rules unknown client", ip: "", allowed: true},
+		{name: "allowed range", allow: []string{"203.0.113.0/24"}, ip: "203.0.113.5", allowed: true},
+		{name: "outside allowed range", allow: []string{"203.0.113.0/24"}, ip: "198.51.100.1"},
+		{name: "allowed address", allow: []string{"203.0.113.5"}, ip: "203.0.113.5", allowed: true},
+		{name: "denied address", deny: []string{"203.0.113.5"}, ip: "203.0.113.5"},
+		{name: "not denied", deny: []string{"203.0.113.5"}, ip: "203.0.113.6", allowed: true},
+		{name: "deny takes precedence", allow: []string{"203.0.113.0/24"}, deny: []string{"203.0.113.5"}, ip: "203.0.113.5"},
+		{name: "unknown client with allow rules", allow: []string{"203.0.113.0/24"}, ip: ""},
+		{name: "unknown client with deny rules", deny: []string{"203.0.113.0/24"}, ip: "", allowed: true},
+		{name: "ipv4 mapped ipv6 client", allow: []string{"203.0.113.0/24"}, ip: "::ffff:203.0.113.5", allowed: true},
+		{name: "ipv6 range", allow: []string{"2001:db8::/32"}, ip: "2001:db8::1", allowed: true},
+		{name: "ipv4 client with ipv6 rules", allow: []string{"2001:db8::/32"}, ip: "203.0.113.5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := ParseRules(test.allow, test.deny)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if allowed := rules.Allows(net.ParseIP(test.ip)); allowed != test.allowed {
+				t.Fatalf("expected %t, got %t", test.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+	global := config.ConfigIpFilter{
+		Deny:           []string{"198.51.100.0/24"},
+		TrustedProxies: []string{"10.0.0.1"},
+	}
+
+	filter, err := NewFilter(global, config.ConfigReverseProxyServerIpFilter{Allow: []string{"198.51.100.0/23"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		allowed      bool
+	}{
+		{name: "allowed by the server", remoteAddr: "198.51.101.1:4711", allowed: true},
+		{name: "denied globally", remoteAddr: "198.51.100.1:4711"},
+		{name: "not allowed by the server", remoteAddr: "203.0.113.5:4711"},
+		{name: "allowed client behind trusted proxy", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.101.1", allowed: true},
+		{name: "denied client behind trusted proxy", remoteAddr: "10.0.0.1:4711", forwardedFor: "198.51.100.1"},
+		{name: "forged header of untrusted peer", remoteAddr: "203.0.113.5:4711", forwardedFor: "198.51.101.1"},
+		{name: "invalid forwarded address", remoteAddr: "10.0.0.1:4711", forwardedFor: "garbage"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, allowed := filter.Check(test.remoteAddr, test.forwardedFor); allowed != test.allowed {
+				t.Fatalf("expected %t, got %t", test.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestNewFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		global config.ConfigIpFilter
+		server config.ConfigReverseProxyServerIpFilter
+	}{
+		{name: "invalid address", global: config.ConfigIpFilter{Allow: []string{"300.0.0.1"}}},
+		{name: "invalid range", server: config.ConfigReverseProxyServerIpFilter{Deny: []string{"10.0.0.0/33"}}},
+		{name: "invalid trusted proxy", global: config.ConfigIpFilter{TrustedProxies: []string{"proxy"}}},
+		{name: "invalid global status", global: config.ConfigIpFilter{Status: 200}},
+		{name: "invalid server status", server: config.ConfigReverseProxyServerIpFilter{Status: 600}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewFilter(test.global, test.server); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
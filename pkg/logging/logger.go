@@ -0,0 +1,356 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	olog "github.com/revx-official/output/log"
+)
+
+// Description:
+//
+//	A subsystem logger.
+//	Every subsystem, e.g. proxy, health or api, has its own log level,
+//	which can be changed at runtime.
+type Logger struct {
+	name     string
+	level    atomic.Uint32
+	explicit atomic.Bool
+}
+
+// The registered subsystem loggers.
+var loggers = make(map[string]*Logger)
+
+// The default log level used by all subsystems without an explicit level.
+var defaultLevel atomic.Uint32
+
+// The internal mutex to control access to the registered loggers.
+var mutex = sync.Mutex{}
+
+// Specifies whether verbose logging was requested on the command line.
+// If set, the configured log levels are ignored.
+var Verbose bool
+
+// The log level names.
+var levelNames = map[olog.LogLevel]string{
+	olog.LevelTrace: "trace",
+	olog.LevelDebug: "debug",
+	olog.LevelInfo:  "info",
+	olog.LevelWarn:  "warn",
+	olog.LevelError: "error",
+	olog.LevelFatal: "fatal",
+}
+
+// Description:
+//
+//	Initializes this package.
+//	Filtering is done by the subsystem loggers, hence the underlying logger logs everything.
+func init() {
+	defaultLevel.Store(olog.LevelInfo)
+	olog.Level = olog.LevelTrace
+}
+
+// Description:
+//
+//	Creates a new subsystem logger, or returns the existing one.
+//
+// Parameters:
+//
+//	name The subsystem name.
+//
+// Returns:
+//
+//	The subsystem logger.
+func NewLogger(name string) *Logger {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	logger, exists := loggers[name]
+
+	if exists {
+		return logger
+	}
+
+	logger = &Logger{name: name}
+	logger.level.Store(defaultLevel.Load())
+
+	loggers[name] = logger
+	return logger
+}
+
+// Description:
+//
+//	Parses a log level name.
+//
+// Parameters:
+//
+//	name The log level name, e.g. debug.
+//
+// Returns:
+//
+//	The log level, or an error, if the name is unknown.
+func ParseLevel(name string) (olog.LogLevel, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("logging: unknown log level: %s", name)
+}
+
+// Description:
+//
+//	Gets the name of a log level.
+//
+// Parameters:
+//
+//	level The log level.
+//
+// Returns:
+//
+//	The log level name.
+func LevelName(level olog.LogLevel) string {
+	return levelNames[level]
+}
+
+// Description:
+//
+//	Sets the default log level.
+//	Applies to all subsystems without an explicit level.
+//
+// Parameters:
+//
+//	level The log level.
+func SetDefaultLevel(level olog.LogLevel) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	defaultLevel.Store(level)
+
+	for _, logger := range loggers {
+		if !logger.explicit.Load() {
+			logger.level.Store(level)
+		}
+	}
+}
+
+// Description:
+//
+//	Gets the default log level.
+//
+// Returns:
+//
+//	The default log level.
+func DefaultLevel() olog.LogLevel {
+	return defaultLevel.Load()
+}
+
+// Description:
+//
+//	Sets the log level of a single subsystem.
+//
+// Parameters:
+//
+//	name 	The subsystem name.
+//	level 	The log level.
+//
+// Returns:
+//
+//	An error, if the subsystem does not exist.
+func SetLevel(name string, level olog.LogLevel) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	logger, exists := loggers[name]
+
+	if !exists {
+		return fmt.Errorf("logging: unknown subsystem: %s", name)
+	}
+
+	logger.level.Store(level)
+	logger.explicit.Store(true)
+
+	return nil
+}
+
+// Description:
+//
+//	Resets the log level of a single subsystem to the default log level.
+//
+// Parameters:
+//
+//	name The subsystem name.
+//
+// Returns:
+//
+//	An error, if the subsystem does not exist.
+func ResetLevel(name string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	logger, exists := loggers[name]
+
+	if !exists {
+		return fmt.Errorf("logging: unknown subsystem: %s", name)
+	}
+
+	logger.level.Store(defaultLevel.Load())
+	logger.explicit.Store(false)
+
+	return nil
+}
+
+// Description:
+//
+//	Gets the current log levels of all subsystems.
+//
+// Returns:
+//
+//	The log level names by subsystem name.
+func Levels() map[string]string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	levels := make(map[string]string)
+
+	for name, logger := range loggers {
+		levels[name] = LevelName(logger.level.Load())
+	}
+
+	return levels
+}
+
+// Description:
+//
+//	Gets the names of all registered subsystems.
+//
+// Returns:
+//
+//	The sorted subsystem names.
+func Subsystems() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	names := make([]string, 0, len(loggers))
+
+	for name := range loggers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Description:
+//
+//	Gets the subsystem name of this logger.
+//
+// Returns:
+//
+//	The subsystem name.
+func (logger *Logger) Name() string {
+	return logger.name
+}
+
+// Description:
+//
+//	Checks whether messages of the given level are logged.
+//
+// Parameters:
+//
+//	level The log level.
+//
+// Returns:
+//
+//	Whether messages of the given level are logged.
+func (logger *Logger) Enabled(level olog.LogLevel) bool {
+	return level >= logger.level.Load()
+}
+
+// Description:
+//
+//	Traces a formatted message.
+//
+// Parameters:
+//
+//	format 	The string used to format the given arguments.
+//	args	The arguments to log.
+func (logger *Logger) Tracef(format string, args ...interface{}) {
+	if logger.Enabled(olog.LevelTrace) {
+		olog.Tracef(format, args...)
+	}
+}
+
+// Description:
+//
+//	Logs a formatted debug message.
+//
+// Parameters:
+//
+//	format 	The string used to format the given arguments.
+//	args	The arguments to log.
+func (logger *Logger) Debugf(format string, args ...interface{}) {
+	if logger.Enabled(olog.LevelDebug) {
+		olog.Debugf(format, args...)
+	}
+}
+
+// Description:
+//
+//	Logs a formatted info message.
+//
+// Parameters:
+//
+//	format 	The string used to format the given arguments.
+//	args	The arguments to log.
+func (logger *Logger) Infof(format string, args ...interface{}) {
+	if logger.Enabled(olog.LevelInfo) {
+		olog.Infof(format, args...)
+	}
+}
+
+// Description:
+//
+//	Logs a formatted warning message.
+//
+// Parameters:
+//
+//	format 	The string used to format the given arguments.
+//	args	The arguments to log.
+func (logger *Logger) Warnf(format string, args ...interface{}) {
+	if logger.Enabled(olog.LevelWarn) {
+		olog.Warnf(format, args...)
+	}
+}
+
+// Description:
+//
+//	Logs a formatted error message.
+//
+// Parameters:
+//
+//	format 	The string used to format the given arguments.
+//	args	The arguments to log.
+func (logger *Logger) Errorf(format string, args ...interface{}) {
+	if logger.Enabled(olog.LevelError) {
+		olog.Errorf(format, args...)
+	}
+}
+
+// Description:
+//
+//	Logs a formatted fatal message.
+//	Fatal messages are always logged.
+//	Automatically calls `os.Exit(1)` afterwards.
+//
+// Parameters:
+//
+//	format 	The string used to format the given arguments.
+//	args	The arguments to log.
+func (logger *Logger) Fatalf(format string, args ...interface{}) {
+	olog.Fatalf(format, args...)
+}
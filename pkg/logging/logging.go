@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"io"
+	nlog "log"
+	"os"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// The logging subsystem logger.
+var log = NewLogger("logging")
+
+// Description:
+//
+//	Initializes logging using the given configuration.
+//	Sets the log levels and redirects the log output.
+//	Log files are reopened on SIGUSR1, where supported.
+//
+// Parameters:
+//
+//	conf The log configuration.
+//
+// Returns:
+//
+//	An error, if the configuration is invalid or the output cannot be opened.
+func InitLogging(conf config.ConfigLog) error {
+	err := applyLevels(conf)
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := openLogOutput(conf)
+
+	if err != nil {
+		return err
+	}
+
+	nlog.SetOutput(writer)
+	watchReopenSignal()
+
+	return nil
+}
+
+// Description:
+//
+//	Applies the configured log levels.
+//	Ignored, if verbose logging was requested on the command line.
+//
+// Parameters:
+//
+//	conf The log configuration.
+//
+// Returns:
+//
+//	An error, if a log level or subsystem is unknown.
+func applyLevels(conf config.ConfigLog) error {
+	if Verbose {
+		return nil
+	}
+
+	if conf.Level != "" {
+		level, err := ParseLevel(conf.Level)
+
+		if err != nil {
+			return err
+		}
+
+		SetDefaultLevel(level)
+	}
+
+	for name, levelName := range conf.Levels {
+		level, err := ParseLevel(levelName)
+
+		if err != nil {
+			return err
+		}
+
+		err = SetLevel(name, level)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Opens the configured log output.
+//	Console colors are stripped from all outputs except the console.
+//
+// Parameters:
+//
+//	conf The log configuration.
+//
+// Returns:
+//
+//	The log output writer, or an error.
+func openLogOutput(conf config.ConfigLog) (io.Writer, error) {
+	switch conf.Output {
+	case "", OutputStdout:
+		return os.Stdout, nil
+	case OutputStderr:
+		return os.Stderr, nil
+	case OutputSyslog:
+		writer, err := openSyslog(conf.Syslog)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return plainWriter{writer: writer}, nil
+	}
+
+	writer, err := OpenOutput(conf.Output, conf.Rotation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plainWriter{writer: writer}, nil
+}
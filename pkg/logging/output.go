@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Constant declarations.
+const (
+	// The standard output.
+	OutputStdout string = "stdout"
+
+	// The standard error output.
+	OutputStderr string = "stderr"
+
+	// The syslog output.
+	OutputSyslog string = "syslog"
+)
+
+// Matches ANSI escape sequences, which are stripped from non console outputs.
+var ansiEscapeSequence = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// All opened log files, which are reopened on request.
+var files = []*RotatingFile{}
+
+// The internal mutex to control access to the opened log files.
+var filesMutex = sync.Mutex{}
+
+// Description:
+//
+//	Opens a log output.
+//	Opened log files are registered for reopening.
+//
+// Parameters:
+//
+//	output 		Either stdout, stderr or a file path.
+//	rotation 	The log file rotation configuration.
+//
+// Returns:
+//
+//	The output writer, or an error.
+func OpenOutput(output string, rotation config.ConfigLogRotation) (io.Writer, error) {
+	switch output {
+	case "", OutputStdout:
+		return os.Stdout, nil
+	case OutputStderr:
+		return os.Stderr, nil
+	}
+
+	file, err := OpenRotatingFile(output, rotation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filesMutex.Lock()
+	defer filesMutex.Unlock()
+
+	files = append(files, file)
+	return file, nil
+}
+
+// Description:
+//
+//	Reopens all opened log files.
+//	Used after the log files have been moved by an external tool, e.g. logrotate.
+func ReopenFiles() {
+	filesMutex.Lock()
+	defer filesMutex.Unlock()
+
+	for _, file := range files {
+		err := file.Reopen()
+
+		if err != nil {
+			log.Errorf("logging: unable to reopen log file: %s", err)
+		}
+	}
+}
+
+// Description:
+//
+//	A writer stripping ANSI escape sequences, e.g. console colors.
+type plainWriter struct {
+	writer io.Writer
+}
+
+// Description:
+//
+//	Writes the given bytes without ANSI escape sequences.
+//
+// Parameters:
+//
+//	buffer The bytes to write.
+//
+// Returns:
+//
+//	The amount of bytes consumed from the buffer, or an error.
+func (writer plainWriter) Write(buffer []byte) (int, error) {
+	_, err := writer.writer.Write(ansiEscapeSequence.ReplaceAll(buffer, nil))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(buffer), nil
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package logging
+
+import (
+	"log/syslog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Ensures the reopen signal is only watched once.
+var watchOnce = sync.Once{}
+
+// Description:
+//
+//	Reopens all log files whenever SIGUSR1 is received.
+func watchReopenSignal() {
+	watchOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGUSR1)
+
+		go func() {
+			for range signals {
+				log.Infof("logging: reopening log files ...")
+				ReopenFiles()
+			}
+		}()
+	})
+}
+
+// Description:
+//
+//	Connects to a syslog daemon.
+//
+// Parameters:
+//
+//	conf The syslog configuration.
+//
+// Returns:
+//
+//	The syslog writer, or an error.
+func openSyslog(conf config.ConfigLogSyslog) (*syslog.Writer, error) {
+	return syslog.Dial(conf.Network, conf.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, conf.Tag)
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package logging
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Description:
+//
+//	Reopening log files on signal is not supported on windows.
+func watchReopenSignal() {
+}
+
+// Description:
+//
+//	Syslog is not supported on windows.
+//
+// Parameters:
+//
+//	conf The syslog configuration.
+//
+// Returns:
+//
+//	Always an error.
+func openSyslog(conf config.ConfigLogSyslog) (io.Writer, error) {
+	return nil, fmt.Errorf("logging: syslog is not supported on windows")
+}
@@ -0,0 +1,325 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Constant declarations.
+const (
+	// The hourly rotation interval.
+	RotationHourly string = "hourly"
+
+	// The daily rotation interval.
+	RotationDaily string = "daily"
+
+	// The time format used as suffix of rotated log files.
+	rotationSuffixFormat string = "2006-01-02T15-04-05.000"
+)
+
+// Description:
+//
+//	A log file which is rotated by size and/or time.
+//	Rotated files are renamed to <path>.<timestamp> and cleaned up according to the retention settings.
+//	All methods are safe for concurrent use.
+type RotatingFile struct {
+	mutex    sync.Mutex
+	path     string
+	rotation config.ConfigLogRotation
+	file     *os.File
+	size     int64
+	period   time.Time
+}
+
+// Description:
+//
+//	Opens a rotating log file.
+//
+// Parameters:
+//
+//	path 		The log file path.
+//	rotation 	The rotation configuration.
+//
+// Returns:
+//
+//	The opened rotating log file, or an error.
+func OpenRotatingFile(path string, rotation config.ConfigLogRotation) (*RotatingFile, error) {
+	if rotation.Interval != "" && rotation.Interval != RotationHourly && rotation.Interval != RotationDaily {
+		return nil, fmt.Errorf("logging: unknown rotation interval: %s", rotation.Interval)
+	}
+
+	file := RotatingFile{path: path, rotation: rotation}
+	err := file.open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// Description:
+//
+//	Writes to the log file.
+//	Rotates the log file beforehand, if required. If the rotation fails, the bytes are still written
+//	to the current log file and the rotation error is returned.
+//
+// Parameters:
+//
+//	buffer The bytes to write.
+//
+// Returns:
+//
+//	The amount of written bytes, or an error.
+func (file *RotatingFile) Write(buffer []byte) (int, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.file == nil {
+		return 0, fmt.Errorf("logging: log file is closed: %s", file.path)
+	}
+
+	var rotateErr error
+
+	if file.shouldRotate(len(buffer), time.Now()) {
+		rotateErr = file.rotate()
+	}
+
+	count, err := file.file.Write(buffer)
+	file.size += int64(count)
+
+	if err == nil {
+		err = rotateErr
+	}
+
+	return count, err
+}
+
+// Description:
+//
+//	Closes and reopens the log file at its path.
+//	Used after the log file has been moved by an external tool, e.g. logrotate.
+//	If the log file cannot be reopened, the current log file stays open.
+//
+// Returns:
+//
+//	An error, if the log file cannot be reopened.
+func (file *RotatingFile) Reopen() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	current := file.file
+	err := file.open()
+
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		current.Close()
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Closes the log file.
+//
+// Returns:
+//
+//	An error, if the log file cannot be closed.
+func (file *RotatingFile) Close() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.file == nil {
+		return nil
+	}
+
+	err := file.file.Close()
+	file.file = nil
+
+	return err
+}
+
+// Description:
+//
+//	Opens the log file at its path in append mode.
+//
+// Returns:
+//
+//	An error, if the log file cannot be opened.
+func (file *RotatingFile) open() error {
+	handle, err := os.OpenFile(file.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+
+	if err != nil {
+		return fmt.Errorf("logging: unable to open log file: %s", err)
+	}
+
+	info, err := handle.Stat()
+
+	if err != nil {
+		handle.Close()
+		return fmt.Errorf("logging: unable to stat log file: %s", err)
+	}
+
+	file.file = handle
+	file.size = info.Size()
+	file.period = file.periodStart(time.Now())
+
+	return nil
+}
+
+// Description:
+//
+//	Checks whether the log file has to be rotated before writing.
+//
+// Parameters:
+//
+//	count 	The amount of bytes about to be written.
+//	now 	The current time.
+//
+// Returns:
+//
+//	Whether the log file has to be rotated.
+func (file *RotatingFile) shouldRotate(count int, now time.Time) bool {
+	maxSize := int64(file.rotation.MaxSize) * 1024 * 1024
+
+	if maxSize > 0 && file.size > 0 && file.size+int64(count) > maxSize {
+		return true
+	}
+
+	return file.rotation.Interval != "" && file.periodStart(now).After(file.period)
+}
+
+// Description:
+//
+//	Gets the start of the rotation period containing the given time.
+//
+// Parameters:
+//
+//	now The time.
+//
+// Returns:
+//
+//	The start of the rotation period.
+func (file *RotatingFile) periodStart(now time.Time) time.Time {
+	year, month, day := now.Date()
+
+	switch file.rotation.Interval {
+	case RotationHourly:
+		return time.Date(year, month, day, now.Hour(), 0, 0, 0, now.Location())
+	case RotationDaily:
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	}
+
+	return time.Time{}
+}
+
+// Description:
+//
+//	Rotates the log file.
+//	Renames the current log file, opens a new one and removes expired backups.
+//	The current log file stays open until the new one is opened, so a failed rotation never stops logging.
+//	After a failure, the rotation is retried once another maximum size has been written or the next period started.
+//
+// Returns:
+//
+//	An error, if the log file cannot be rotated.
+func (file *RotatingFile) rotate() error {
+	backup := file.path + "." + time.Now().Format(rotationSuffixFormat)
+	err := os.Rename(file.path, backup)
+
+	if err != nil && !os.IsNotExist(err) {
+		file.postpone()
+		return fmt.Errorf("logging: unable to rotate log file: %s", err)
+	}
+
+	current := file.file
+	err = file.open()
+
+	if err != nil {
+		file.postpone()
+		return err
+	}
+
+	current.Close()
+	file.cleanup()
+
+	return nil
+}
+
+// Description:
+//
+//	Postpones the next rotation after a failed rotation.
+func (file *RotatingFile) postpone() {
+	file.size = 0
+	file.period = file.periodStart(time.Now())
+}
+
+// Description:
+//
+//	Removes rotated log files exceeding the retention settings.
+func (file *RotatingFile) cleanup() {
+	backups := file.backups()
+	cutoff := time.Now().AddDate(0, 0, -int(file.rotation.MaxAge))
+
+	for index, backup := range backups {
+		expired := file.rotation.MaxAge > 0 && backup.time.Before(cutoff)
+		exceeding := file.rotation.MaxBackups > 0 && index >= int(file.rotation.MaxBackups)
+
+		if expired || exceeding {
+			os.Remove(backup.path)
+		}
+	}
+}
+
+// Description:
+//
+//	A rotated log file.
+type rotatedFile struct {
+	path string
+	time time.Time
+}
+
+// Description:
+//
+//	Lists all rotated log files, newest first.
+//
+// Returns:
+//
+//	The rotated log files.
+func (file *RotatingFile) backups() []rotatedFile {
+	matches, err := filepath.Glob(file.path + ".*")
+
+	if err != nil {
+		return nil
+	}
+
+	backups := []rotatedFile{}
+
+	for _, match := range matches {
+		suffix := strings.TrimPrefix(match, file.path+".")
+		timeStamp, err := time.ParseInLocation(rotationSuffixFormat, suffix, time.Local)
+
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, rotatedFile{path: match, time: timeStamp})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	return backups
+}
@@ -1,14 +1,26 @@
 package proxy
 
 import (
+	"fmt"
 	"sync"
 )
 
+// Constant declarations.
+const (
+	// Passes requests to the healthy upstreams in turn.
+	BalancingRoundRobin string = "round-robin"
+
+	// Passes requests to the healthy upstream with the least active connections.
+	// Open websockets count as active connections.
+	BalancingLeastConnections string = "least-connections"
+)
+
 // Description:
 //
 //	The load balancer information is used by any proxy request handler.
 //	It provides information used to select the proxy, which is going to handle the request.
 type LoadBalancerInfo struct {
+	Strategy           string     `json:"strategy"`           // The load balancing strategy.
 	ProxyInstanceIndex uint32     `json:"proxyInstanceIndex"` // The currently active proxy instance index.
 	Mutex              sync.Mutex `json:"-"`                  // The mutex used to lock operations on the index.
 }
@@ -23,3 +35,116 @@ type LoadBalancerInfo struct {
 func NewLoadBalancerInfo() *LoadBalancerInfo {
 	return &LoadBalancerInfo{}
 }
+
+// Description:
+//
+//	Gets the load balancing strategy of a server configuration.
+//
+// Parameters:
+//
+//	strategy The configured strategy.
+//
+// Returns:
+//
+//	The load balancing strategy, or an error, if the strategy is not supported.
+func BalancingStrategy(strategy string) (string, error) {
+	switch strategy {
+	case "":
+		return BalancingRoundRobin, nil
+	case BalancingRoundRobin, BalancingLeastConnections:
+		return strategy, nil
+	}
+
+	return "", fmt.Errorf("proxy: unsupported load balancing strategy: %s", strategy)
+}
+
+// Description:
+//
+//	Selects the upstream handling the next request.
+//	Unhealthy upstreams are skipped. For websockets, upstreams which reached the
+//	maximum amount of websockets are skipped as well.
+//	The selected upstream is marked as having one more active connection,
+//	which must be released by calling ReleaseUpstream.
+//
+// Parameters:
+//
+//	prox 		The reverse proxy.
+//	webSocket 	Whether the request is a websocket upgrade.
+//
+// Returns:
+//
+//	The selected upstream, or nil, if no upstream is available.
+func SelectUpstream(prox *ReverseProxyServerInfo, webSocket bool) *ReverseProxyServerUpstreamInfo {
+	prox.BalancerInfo.Mutex.Lock()
+	defer prox.BalancerInfo.Mutex.Unlock()
+
+	instanceCount := uint32(len(prox.Upstreams))
+
+	if instanceCount == 0 {
+		return nil
+	}
+
+	start := prox.BalancerInfo.ProxyInstanceIndex % instanceCount
+	selected := -1
+
+	// Iterating from the current index lets ties between upstreams rotate.
+	for offset := uint32(0); offset < instanceCount; offset++ {
+		index := (start + offset) % instanceCount
+		instance := prox.Upstreams[index]
+
+		if !instance.HealthStats.Healthy {
+			continue
+		}
+
+		limit := int64(prox.WebSocketInfo.MaxConnections)
+
+		if webSocket && limit > 0 && instance.Stats.WebSockets() >= limit {
+			continue
+		}
+
+		if selected == -1 {
+			selected = int(index)
+
+			if prox.BalancerInfo.Strategy == BalancingRoundRobin {
+				break
+			}
+
+			continue
+		}
+
+		if instance.connections.Load() < prox.Upstreams[selected].connections.Load() {
+			selected = int(index)
+		}
+	}
+
+	if selected == -1 {
+		return nil
+	}
+
+	prox.BalancerInfo.ProxyInstanceIndex = (uint32(selected) + 1) % instanceCount
+
+	instance := prox.Upstreams[selected]
+	instance.connections.Add(1)
+
+	if webSocket {
+		instance.Stats.BeginWebSocket()
+	}
+
+	return instance
+}
+
+// Description:
+//
+//	Releases the active connection of an upstream, marked by SelectUpstream.
+//
+// Parameters:
+//
+//	instance 	The upstream.
+//	webSocket 	Whether the request was a websocket upgrade.
+func ReleaseUpstream(instance *ReverseProxyServerUpstreamInfo, webSocket bool) {
+	instance.connections.Add(-1)
+
+	if webSocket {
+		instance.Stats.EndWebSocket()
+	}
+}
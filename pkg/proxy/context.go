@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+)
+
+// Description:
+//
+//	The context key type used to store request information.
+type requestInfoContextKey struct{}
+
+// Description:
+//
+//	Holds information about a single proxied request.
+//	The information is shared between the proxy handler and its middlewares.
+type ProxyRequestInfo struct {
+	Server    string                          // The name of the server handling the request.
+	Upstream  *ReverseProxyServerUpstreamInfo // The upstream chosen by the load balancer, if any.
+	RequestId string                          // The request id.
+	User      string                          // The user verified by basic authentication, if any.
+}
+
+// Description:
+//
+//	Attaches new request information to the given request.
+//	If the request already carries request information, it is reused.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The request carrying the information, and the information itself.
+func WithRequestInfo(request *http.Request) (*http.Request, *ProxyRequestInfo) {
+	info := RequestInfo(request)
+
+	if info != nil {
+		return request, info
+	}
+
+	info = &ProxyRequestInfo{}
+	ctx := context.WithValue(request.Context(), requestInfoContextKey{}, info)
+
+	return request.WithContext(ctx), info
+}
+
+// Description:
+//
+//	Gets the request information attached to the given request.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The request information, or nil, if there is none.
+func RequestInfo(request *http.Request) *ProxyRequestInfo {
+	info, _ := request.Context().Value(requestInfoContextKey{}).(*ProxyRequestInfo)
+	return info
+}
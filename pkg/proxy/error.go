@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Description:
+//
+//	Represents an error response written by the proxy itself.
+type ProxyErrorResponse struct {
+	Message   string `json:"message"`             // The error message.
+	RequestId string `json:"requestId,omitempty"` // The request id, if any.
+}
+
+// Description:
+//
+//	Writes a json error response.
+//	Includes the request id, if the request carries one.
+//
+// Parameters:
+//
+//	response 	The response writer.
+//	request 	The request.
+//	statusCode 	The response status code.
+//	message 	The error message.
+func WriteError(response http.ResponseWriter, request *http.Request, statusCode int, message string) {
+	body := ProxyErrorResponse{Message: message}
+
+	if info := RequestInfo(request); info != nil {
+		body.RequestId = info.RequestId
+	}
+
+	response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	response.WriteHeader(statusCode)
+
+	json.NewEncoder(response).Encode(body)
+}
+
+// Description:
+//
+//	Handles errors passing a request to an upstream.
+//	Used as error handler of every upstream reverse proxy.
+//
+// Parameters:
+//
+//	response 	The response writer.
+//	request 	The request.
+//	err 		The error.
+func handleUpstreamError(response http.ResponseWriter, request *http.Request, err error) {
+	log.Warnf("proxy: unable to pass request: %s %s: %s%s", request.Method, request.URL.Path, err, RequestIdSuffix(request))
+	WriteError(response, request, http.StatusBadGateway, "Bad gateway.")
+}
+
+// Description:
+//
+//	Removes the request id header from an upstream response.
+//	The request id header is set by revx itself, hence the upstream value would duplicate it.
+//
+// Parameters:
+//
+//	response The upstream response.
+//
+// Returns:
+//
+//	Always nil.
+func removeUpstreamRequestId(response *http.Response) error {
+	if config.Global.RequestId.Enabled {
+		response.Header.Del(config.Global.RequestId.Header)
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Formats the request id of a request as log message suffix.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The log message suffix, or an empty string, if the request carries no request id.
+func RequestIdSuffix(request *http.Request) string {
+	info := RequestInfo(request)
+
+	if info == nil || info.RequestId == "" {
+		return ""
+	}
+
+	return " (request id: " + info.RequestId + ")"
+}
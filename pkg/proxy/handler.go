@@ -3,36 +3,46 @@ package proxy
 import (
 	"net/http"
 
-	"github.com/revx-official/output/log"
 	"github.com/revx-official/revx/pkg/router"
 )
 
 // Description:
 //
 //	Represents the endpoint handler for any reverse proxy endpoint.
-//	This handler performs round robin load balancing across the proxy instances.
+//	This handler load balances across the proxy instances, using the strategy of the proxy.
 //
 // Parameters:
 //
 //	prox The reverse proxy.
 func LoadBalancingHandler(prox *ReverseProxyServerInfo) router.RouterProxyHandlerFunc {
 	return func(request *http.Request, response http.ResponseWriter) {
-		log.Infof("proxy: pass %s %s", request.Method, request.URL.Path)
+		request, info := WithRequestInfo(request)
+		info.Server = prox.Name
 
-		prox.BalancerInfo.Mutex.Lock()
+		log.Tracef("proxy: pass %s %s%s", request.Method, request.URL.Path, RequestIdSuffix(request))
 
-		instanceCount := uint32(len(prox.Upstreams))
-		proxyIndex := prox.BalancerInfo.ProxyInstanceIndex % instanceCount
+		webSocket := IsWebSocketRequest(request)
 
-		instance := prox.Upstreams[proxyIndex]
+		if webSocket && !prox.WebSocketInfo.Enabled {
+			WriteError(response, request, http.StatusForbidden, "Websockets are not allowed.")
+			return
+		}
+
+		instance := SelectUpstream(prox, webSocket)
 
-		for !instance.HealthStats.Healthy {
-			proxyIndex = (proxyIndex + 1) % instanceCount
-			instance = prox.Upstreams[proxyIndex]
+		if instance == nil {
+			log.Warnf("proxy: no upstream available: %s%s", prox.Name, RequestIdSuffix(request))
+			WriteError(response, request, http.StatusServiceUnavailable, "Service unavailable.")
+			return
 		}
 
-		prox.BalancerInfo.ProxyInstanceIndex = (proxyIndex + 1) % instanceCount
-		prox.BalancerInfo.Mutex.Unlock()
+		defer ReleaseUpstream(instance, webSocket)
+		info.Upstream = instance
+
+		if webSocket {
+			serveWebSocket(prox, instance, response, request)
+			return
+		}
 
 		instance.ReverseProxy.ServeHTTP(response, request)
 	}
@@ -2,8 +2,6 @@ package proxy
 
 import (
 	"sync"
-
-	"github.com/revx-official/output/log"
 )
 
 // Description:
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Constant declarations.
+const (
+	// The default maximum amount of idle connections per upstream.
+	poolDefaultMaxIdleConnections uint32 = 32
+
+	// The default time in milliseconds after which an idle connection is closed.
+	poolDefaultIdleTimeout uint32 = 90000
+
+	// The default time in milliseconds to wait for a connection to be established.
+	poolDefaultConnectTimeout uint32 = 30000
+
+	// The default interval in milliseconds of tcp keep-alive probes.
+	poolDefaultKeepAliveInterval uint32 = 30000
+
+	// The default size in bytes of the connection read and write buffers.
+	poolDefaultBufferSize uint32 = 4096
+)
+
+// Description:
+//
+//	Holds information about the connection pool of every upstream of a proxy.
+//	Values only supported by HTTP/1 are omitted for HTTP/2 upstreams.
+type ReverseProxyServerConnectionPoolInfo struct {
+	MaxIdleConnections uint32 `json:"maxIdleConnections,omitempty"` // The maximum amount of idle connections per upstream.
+	MaxConnections     uint32 `json:"maxConnections"`               // The maximum amount of connections per upstream, 0 if unlimited.
+	IdleTimeout        uint32 `json:"idleTimeout"`                  // The time in milliseconds after which an idle connection is closed.
+	ConnectTimeout     uint32 `json:"connectTimeout"`               // The time in milliseconds to wait for a connection to be established.
+	KeepAlive          bool   `json:"keepAlive"`                    // Whether connections are reused for subsequent requests.
+	KeepAliveInterval  uint32 `json:"keepAliveInterval"`            // The interval in milliseconds of tcp keep-alive probes.
+	ReadBufferSize     uint32 `json:"readBufferSize,omitempty"`     // The size in bytes of the connection read buffer.
+	WriteBufferSize    uint32 `json:"writeBufferSize,omitempty"`    // The size in bytes of the connection write buffer.
+}
+
+// Description:
+//
+//	A connection to an upstream, which reports its closing to the upstream statistics.
+type pooledConn struct {
+	net.Conn
+	stats *ReverseProxyServerUpstreamStats
+	once  sync.Once
+}
+
+// Description:
+//
+//	Creates the connection pool information of a server configuration.
+//	Applies the defaults for all values not configured.
+//	HTTP/2 connections are multiplexed, so the idle connection and buffer settings of HTTP/1 do not apply to them.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The connection pool information.
+func connectionPoolInfo(conf config.ConfigReverseProxyServer) ReverseProxyServerConnectionPoolInfo {
+	pool := conf.ConnectionPool
+
+	info := ReverseProxyServerConnectionPoolInfo{
+		MaxIdleConnections: valueOrDefault(pool.MaxIdleConnections, poolDefaultMaxIdleConnections),
+		MaxConnections:     pool.MaxConnections,
+		IdleTimeout:        valueOrDefault(pool.IdleTimeout, poolDefaultIdleTimeout),
+		ConnectTimeout:     valueOrDefault(pool.ConnectTimeout, poolDefaultConnectTimeout),
+		KeepAlive:          pool.KeepAlive == nil || *pool.KeepAlive,
+		KeepAliveInterval:  valueOrDefault(pool.KeepAliveInterval, poolDefaultKeepAliveInterval),
+		ReadBufferSize:     valueOrDefault(pool.ReadBufferSize, poolDefaultBufferSize),
+		WriteBufferSize:    valueOrDefault(pool.WriteBufferSize, poolDefaultBufferSize),
+	}
+
+	if UpstreamProtocol(conf) != ProtocolHttp1 {
+		info.MaxIdleConnections = 0
+		info.ReadBufferSize = 0
+		info.WriteBufferSize = 0
+	}
+
+	return info
+}
+
+// Description:
+//
+//	Checks whether the connection pool configuration of a server is supported by its upstream protocol.
+//	HTTP/2 upstreams reject the settings, which only apply to HTTP/1 connections.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	An error, if a setting is not supported.
+func validateConnectionPool(conf config.ConfigReverseProxyServer) error {
+	protocol := UpstreamProtocol(conf)
+	pool := conf.ConnectionPool
+
+	if protocol == ProtocolHttp1 {
+		return nil
+	}
+
+	unsupported := []string{}
+
+	if pool.MaxIdleConnections != 0 {
+		unsupported = append(unsupported, "max-idle-connections")
+	}
+
+	if pool.KeepAlive != nil && !*pool.KeepAlive {
+		unsupported = append(unsupported, "keep-alive: false")
+	}
+
+	if pool.ReadBufferSize != 0 {
+		unsupported = append(unsupported, "read-buffer-size")
+	}
+
+	if pool.WriteBufferSize != 0 {
+		unsupported = append(unsupported, "write-buffer-size")
+	}
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf("proxy: connection pool settings not supported by protocol %s: %s", protocol, strings.Join(unsupported, ", "))
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Gets a configured value or its default.
+//
+// Parameters:
+//
+//	value 			The configured value.
+//	defaultValue 	The default value.
+//
+// Returns:
+//
+//	The configured value, or the default value, if the configured value is 0.
+func valueOrDefault(value uint32, defaultValue uint32) uint32 {
+	if value == 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// Description:
+//
+//	Creates the dial function of an upstream connection pool.
+//	Every established connection and every failed attempt is recorded in the upstream statistics.
+//
+// Parameters:
+//
+//	pool 	The connection pool information.
+//	socket 	The unix socket path to dial, or empty to dial the requested address.
+//	stats 	The upstream statistics.
+//
+// Returns:
+//
+//	The dial function.
+func poolDialer(pool ReverseProxyServerConnectionPoolInfo, socket string, stats *ReverseProxyServerUpstreamStats) func(context.Context, string, string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(pool.ConnectTimeout) * time.Millisecond,
+		KeepAlive: time.Duration(pool.KeepAliveInterval) * time.Millisecond,
+	}
+
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if socket != "" {
+			network = "unix"
+			address = socket
+		}
+
+		conn, err := dialer.DialContext(ctx, network, address)
+		stats.RecordDial(err == nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &pooledConn{Conn: conn, stats: stats}, nil
+	}
+}
+
+// Description:
+//
+//	Creates the TLS dial function of an upstream HTTP/2 connection pool.
+//
+// Parameters:
+//
+//	dial The dial function of the connection pool.
+//
+// Returns:
+//
+//	The TLS dial function.
+func poolTlsDialer(dial func(context.Context, string, string) (net.Conn, error)) func(context.Context, string, string, *tls.Config) (net.Conn, error) {
+	return func(ctx context.Context, network string, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		err = tlsConn.HandshakeContext(ctx)
+
+		if err == nil && tlsConn.ConnectionState().NegotiatedProtocol != "h2" {
+			err = fmt.Errorf("proxy: upstream does not support h2: %s", address)
+		}
+
+		if err != nil {
+			tlsConn.Close()
+			return nil, err
+		}
+
+		return tlsConn, nil
+	}
+}
+
+// Description:
+//
+//	Closes the connection and records the closing once.
+//
+// Returns:
+//
+//	An error, if the connection cannot be closed.
+func (conn *pooledConn) Close() error {
+	conn.once.Do(conn.stats.RecordClose)
+	return conn.Conn.Close()
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"golang.org/x/net/http2"
+)
+
+// Constant declarations.
+const (
+	// HTTP/1.1 over cleartext or TLS.
+	ProtocolHttp1 string = "http1"
+
+	// HTTP/2 over TLS.
+	ProtocolH2 string = "h2"
+
+	// HTTP/2 over cleartext, using prior knowledge.
+	ProtocolH2c string = "h2c"
+
+	// Raw tcp connections, used by stream servers.
+	ProtocolTcp string = "tcp"
+
+	// Raw udp datagrams, used by stream servers.
+	ProtocolUdp string = "udp"
+
+	// The url scheme of upstreams listening on unix domain sockets.
+	SchemeUnix string = "unix"
+
+	// The host used in request urls of unix domain socket upstreams.
+	// Requests keep the host of the client, so it only applies to health checks.
+	unixHost string = "localhost"
+)
+
+// Description:
+//
+//	Gets the upstream protocol of a server configuration.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The upstream protocol, http1 if none is configured.
+func UpstreamProtocol(conf config.ConfigReverseProxyServer) string {
+	if conf.Protocol == "" {
+		return ProtocolHttp1
+	}
+
+	return conf.Protocol
+}
+
+// Description:
+//
+//	Creates the transport used to connect to an upstream.
+//	Every upstream uses its own transport, i.e. its own connection pool, tuned by the connection pool configuration.
+//	Https upstreams are verified using the upstream TLS configuration of the server.
+//	Unix domain socket upstreams are dialed at the socket path, using cleartext http1 or h2c.
+//
+// Parameters:
+//
+//	target 	The upstream url.
+//	conf 	The server configuration.
+//	stats 	The upstream statistics, recording the connections of the pool.
+//
+// Returns:
+//
+//	The transport, or an error, if the protocol is not supported for the upstream.
+func newUpstreamTransport(target *url.URL, conf config.ConfigReverseProxyServer, stats *ReverseProxyServerUpstreamStats) (http.RoundTripper, error) {
+	protocol := UpstreamProtocol(conf)
+	tlsConfig, err := upstreamTlsConfig(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateConnectionPool(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pool := connectionPoolInfo(conf)
+	socket := ""
+
+	if target.Scheme == SchemeUnix {
+		if protocol == ProtocolH2 {
+			return nil, fmt.Errorf("proxy: protocol h2 is not supported for unix socket upstreams: %s", target)
+		}
+
+		socket = target.Path
+	}
+
+	dial := poolDialer(pool, socket, stats)
+	idleTimeout := time.Duration(pool.IdleTimeout) * time.Millisecond
+
+	switch protocol {
+	case ProtocolHttp1:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		transport.DialContext = dial
+		transport.MaxIdleConns = int(pool.MaxIdleConnections)
+		transport.MaxIdleConnsPerHost = int(pool.MaxIdleConnections)
+		transport.MaxConnsPerHost = int(pool.MaxConnections)
+		transport.IdleConnTimeout = idleTimeout
+		transport.DisableKeepAlives = !pool.KeepAlive
+		transport.ReadBufferSize = int(pool.ReadBufferSize)
+		transport.WriteBufferSize = int(pool.WriteBufferSize)
+
+		// A non-nil map prevents the transport from negotiating HTTP/2.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+
+		return transport, nil
+	case ProtocolH2:
+		if target.Scheme != "https" {
+			return nil, fmt.Errorf("proxy: protocol h2 requires an https upstream: %s", target)
+		}
+
+		transport, err := newHttp2Transport(pool)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = tlsConfig
+		transport.DialTLSContext = poolTlsDialer(dial)
+
+		return transport, nil
+	case ProtocolH2c:
+		if target.Scheme != "http" && target.Scheme != SchemeUnix {
+			return nil, fmt.Errorf("proxy: protocol h2c requires an http upstream: %s", target)
+		}
+
+		transport, err := newHttp2Transport(pool)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transport.AllowHTTP = true
+		transport.DialTLSContext = func(ctx context.Context, network string, address string, _ *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, address)
+		}
+
+		return transport, nil
+	}
+
+	return nil, fmt.Errorf("proxy: unsupported upstream protocol: %s", protocol)
+}
+
+// Description:
+//
+//	Creates an HTTP/2 transport.
+//	The HTTP/2 transport takes its idle connection timeout from a linked HTTP/1 transport,
+//	which is never used for requests itself. The HTTP/2 transport dials its connections on its own.
+//	Idle connections are checked by pings in the keep-alive interval. With a connection limit,
+//	requests wait for a free stream of the existing connection, instead of opening further connections.
+//
+// Parameters:
+//
+//	pool The connection pool information.
+//
+// Returns:
+//
+//	The transport, or an error, if the transports cannot be linked.
+func newHttp2Transport(pool ReverseProxyServerConnectionPoolInfo) (*http2.Transport, error) {
+	idleTimeout := time.Duration(pool.IdleTimeout) * time.Millisecond
+	transport, err := http2.ConfigureTransports(&http.Transport{IdleConnTimeout: idleTimeout})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The linked pool only serves connections dialed by the HTTP/1 transport.
+	transport.ConnPool = nil
+	transport.ReadIdleTimeout = time.Duration(pool.KeepAliveInterval) * time.Millisecond
+	transport.StrictMaxConcurrentStreams = pool.MaxConnections > 0
+
+	return transport, nil
+}
+
+// Description:
+//
+//	Gets the url requests are sent to for an upstream.
+//	For unix domain socket upstreams, e.g. unix:///run/app.sock, this is a cleartext http url
+//	without a path, so the request path is passed unchanged. All other upstreams use the target url.
+//
+// Parameters:
+//
+//	target The upstream url.
+//
+// Returns:
+//
+//	The request url, or an error, if the unix socket url has no absolute socket path.
+func upstreamRequestUrl(target *url.URL) (*url.URL, error) {
+	if target.Scheme != SchemeUnix {
+		return target, nil
+	}
+
+	if target.Host != "" || !strings.HasPrefix(target.Path, "/") {
+		return nil, fmt.Errorf("proxy: unix socket upstream requires an absolute path, e.g. unix:///run/app.sock: %s", target)
+	}
+
+	return &url.URL{Scheme: "http", Host: unixHost}, nil
+}
@@ -4,22 +4,32 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sync/atomic"
 
 	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
 )
 
+// The proxy subsystem logger.
+var log = logging.NewLogger("proxy")
+
 // Description:
 //
 //	Represents a reverse proxy. A reverse proxy can consist of multiple reverse proxy instances,
 //	where every instance represents exactly one server.
 //	A reverse proxy can load balance across multiple instances of the same service.
 type ReverseProxyServerInfo struct {
-	Name            string                            `json:"name"`            // The name of the proxy.
-	Context         string                            `json:"context"`         // The context path.
-	AllowedMethods  []string                          `json:"allowedMethods"`  // All allowed http methods.
-	Upstreams       []ReverseProxyServerUpstreamInfo  `json:"upstreams"`       // The individual reverse proxy instances.
-	HealthCheckInfo ReverseProxyServerHealthCheckInfo `json:"healthCheckInfo"` // The reverse proxy health check information.
-	BalancerInfo    LoadBalancerInfo                  `json:"balancerInfo"`    // Information used by the load balancer.
+	Name               string                               `json:"name"`               // The name of the proxy.
+	Context            string                               `json:"context"`            // The context path.
+	Listen             string                               `json:"listen,omitempty"`   // The listen address, only used by stream servers.
+	AllowedMethods     []string                             `json:"allowedMethods"`     // All allowed http methods.
+	Protocol           string                               `json:"protocol"`           // The protocol used to connect to the upstreams.
+	Upstreams          []*ReverseProxyServerUpstreamInfo    `json:"upstreams"`          // The individual reverse proxy instances.
+	HealthCheckInfo    ReverseProxyServerHealthCheckInfo    `json:"healthCheckInfo"`    // The reverse proxy health check information.
+	WebSocketInfo      ReverseProxyServerWebSocketInfo      `json:"webSocketInfo"`      // The reverse proxy websocket information.
+	ConnectionPoolInfo ReverseProxyServerConnectionPoolInfo `json:"connectionPoolInfo"` // The connection pool information of every upstream.
+	FlushInterval      int64                                `json:"flushInterval"`      // The response flush interval in milliseconds, -1 if responses are flushed after every write.
+	BalancerInfo       LoadBalancerInfo                     `json:"balancerInfo"`       // Information used by the load balancer.
 }
 
 // Description:
@@ -30,9 +40,12 @@ type ReverseProxyServerInfo struct {
 //	Each instance stores a bunch of stats related to health checks.
 type ReverseProxyServerUpstreamInfo struct {
 	TargetUrl    *url.URL                              `json:"targetUrl"`   // The url which is targeted by the reverse proxy.
+	RequestUrl   *url.URL                              `json:"-"`           // The url requests are sent to, differs from the target url for unix socket upstreams.
 	ReverseProxy *httputil.ReverseProxy                `json:"-"`           // The http reverse proxy.
+	Transport    http.RoundTripper                     `json:"-"`           // The transport connecting to the target, e.g. used by health checks.
 	HealthStats  ReverseProxyServerUpstreamHealthStats `json:"healthStats"` // The instance health stats.
-	Stats        ReverseProxyServerUpstreamStats       `json:"stats"`       // The instance statistics.
+	Stats        *ReverseProxyServerUpstreamStats      `json:"stats"`       // The instance statistics.
+	connections  atomic.Int64                          // The amount of active connections, used by the load balancer.
 }
 
 // Description:
@@ -44,6 +57,18 @@ type ReverseProxyServerHealthCheckInfo struct {
 	Endpoint string `json:"endpoint"` // The health check endpoint.
 	Interval uint32 `json:"interval"` // The health check interval.
 	Fails    uint32 `json:"fails"`    // The maximum amount of fails until a service is considered as unhealthy.
+	Send     string `json:"send"`     // The datagram sent to udp upstreams.
+	Expect   string `json:"expect"`   // The text the reply of an udp upstream has to contain.
+}
+
+// Description:
+//
+//	Holds information about the websocket handling of a proxy.
+type ReverseProxyServerWebSocketInfo struct {
+	Enabled        bool   `json:"enabled"`        // Whether websocket upgrades are passed to the upstreams.
+	IdleTimeout    uint32 `json:"idleTimeout"`    // The time in milliseconds after which an idle websocket is closed.
+	MaxMessageSize uint32 `json:"maxMessageSize"` // The maximum size in bytes of a single client message.
+	MaxConnections uint32 `json:"maxConnections"` // The maximum amount of concurrent websockets per upstream.
 }
 
 // Description:
@@ -55,13 +80,6 @@ type ReverseProxyServerUpstreamHealthStats struct {
 	Error            string `json:"error"`            // The error, if there is one.
 }
 
-// Descriptions:
-//
-//	Tracks some statistics about a server upstream.
-type ReverseProxyServerUpstreamStats struct {
-	AverageRequestTime float32 `json:"averageRequestTime"`
-}
-
 // Description:
 //
 //	Creates a new reverse proxy based on the given configuration.
@@ -80,19 +98,40 @@ func NewReverseProxyServer(conf config.ConfigReverseProxyServer) (*ReverseProxyS
 	proxy.Name = conf.Name
 	proxy.Context = conf.Context
 	proxy.AllowedMethods = conf.AllowedMethods
+	proxy.Protocol = UpstreamProtocol(conf)
 
 	proxy.HealthCheckInfo.Endpoint = conf.HealthCheck.Endpoint
 	proxy.HealthCheckInfo.Interval = conf.HealthCheck.Interval
 	proxy.HealthCheckInfo.Fails = conf.HealthCheck.Fails
 
+	proxy.WebSocketInfo.Enabled = conf.WebSocket.Enabled == nil || *conf.WebSocket.Enabled
+	proxy.WebSocketInfo.IdleTimeout = conf.WebSocket.IdleTimeout
+	proxy.WebSocketInfo.MaxMessageSize = conf.WebSocket.MaxMessageSize
+	proxy.WebSocketInfo.MaxConnections = conf.WebSocket.MaxConnections
+
+	proxy.ConnectionPoolInfo = connectionPoolInfo(conf)
+	proxy.FlushInterval = flushInterval(conf).Milliseconds()
+
+	if flushInterval(conf) < 0 {
+		proxy.FlushInterval = -1
+	}
+
+	strategy, err := BalancingStrategy(conf.LoadBalancing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	proxy.BalancerInfo.Strategy = strategy
+
 	for _, upstream := range conf.Upstreams {
-		instance, err := NewReverseProxyServerUpstream(upstream)
+		instance, err := NewReverseProxyServerUpstream(upstream, conf)
 
 		if err != nil {
 			return nil, err
 		}
 
-		proxy.Upstreams = append(proxy.Upstreams, *instance)
+		proxy.Upstreams = append(proxy.Upstreams, instance)
 	}
 
 	RegisterProxy(&proxy)
@@ -106,30 +145,45 @@ func NewReverseProxyServer(conf config.ConfigReverseProxyServer) (*ReverseProxyS
 //
 // Parameters:
 //
-//	remote The url which should be targeted by the reverse proxy.
-func NewReverseProxyServerUpstream(remote string) (*ReverseProxyServerUpstreamInfo, error) {
+//	remote 	The url which should be targeted by the reverse proxy.
+//	conf 	The configuration of the server the upstream belongs to.
+func NewReverseProxyServerUpstream(remote string, conf config.ConfigReverseProxyServer) (*ReverseProxyServerUpstreamInfo, error) {
 	target, err := url.Parse(remote)
 
 	if err != nil {
 		return nil, err
 	}
 
+	requestUrl, err := upstreamRequestUrl(target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats := NewReverseProxyServerUpstreamStats()
+	transport, err := newUpstreamTransport(target, conf, stats)
+
+	if err != nil {
+		return nil, err
+	}
+
 	upstream := ReverseProxyServerUpstreamInfo{}
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
-	proxy.Transport = NewReverseProxyTransport(&upstream, http.DefaultTransport)
+	proxy := httputil.NewSingleHostReverseProxy(requestUrl)
+	proxy.Transport = NewReverseProxyTransport(&upstream, transport)
+	proxy.ErrorHandler = handleUpstreamError
+	proxy.ModifyResponse = modifyUpstreamResponse
+	proxy.FlushInterval = flushInterval(conf)
 
 	healthStats := ReverseProxyServerUpstreamHealthStats{
 		Healthy:          true,
 		ConsecutiveFails: 0,
 	}
 
-	stats := ReverseProxyServerUpstreamStats{
-		AverageRequestTime: 0,
-	}
-
 	upstream.TargetUrl = target
+	upstream.RequestUrl = requestUrl
 	upstream.ReverseProxy = proxy
+	upstream.Transport = transport
 	upstream.HealthStats = healthStats
 	upstream.Stats = stats
 
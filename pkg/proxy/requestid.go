@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// Constant declarations.
+const (
+	// The maximum length of an accepted incoming request id.
+	maxRequestIdLength int = 128
+)
+
+// Description:
+//
+//	Middleware assigning a request id to every request.
+//	The request id is taken from the configured header, if present and valid, or generated otherwise.
+//	It is forwarded to the upstream and returned to the client in the same header.
+//	If request ids are disabled, the handler is returned as is.
+//
+// Parameters:
+//
+//	conf The request id configuration.
+//
+// Returns:
+//
+//	The middleware.
+func RequestIdMiddleware(conf config.ConfigRequestId) router.RouterProxyMiddlewareFunc {
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		if !conf.Enabled {
+			return handler
+		}
+
+		return func(request *http.Request, response http.ResponseWriter) {
+			request, info := WithRequestInfo(request)
+			id := request.Header.Get(conf.Header)
+
+			if !conf.TrustIncoming || !IsValidRequestId(id) {
+				id = NewRequestId()
+			}
+
+			info.RequestId = id
+
+			request.Header.Set(conf.Header, id)
+			response.Header().Set(conf.Header, id)
+
+			handler(request, response)
+		}
+	}
+}
+
+// Description:
+//
+//	Generates a new random request id in the UUID version 4 format.
+//
+// Returns:
+//
+//	The generated request id.
+func NewRequestId() string {
+	id := [16]byte{}
+	rand.Read(id[:])
+
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// Description:
+//
+//	Checks whether an incoming request id is acceptable.
+//	Only short, printable ascii ids are accepted, to keep logs intact.
+//
+// Parameters:
+//
+//	id The request id.
+//
+// Returns:
+//
+//	Whether the request id is acceptable.
+func IsValidRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+
+	for _, character := range id {
+		if character <= ' ' || character > '~' {
+			return false
+		}
+	}
+
+	return true
+}
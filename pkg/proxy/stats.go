@@ -0,0 +1,558 @@
+package proxy
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// Constant declarations.
+const (
+	// The duration covered by a single statistics slot.
+	statsSlotDuration = 10 * time.Second
+
+	// The amount of statistics slots, covering the largest sliding window (15 minutes).
+	statsSlotCount = 90
+
+	// The upper bound of the smallest latency bucket.
+	statsLatencyMin = 100 * time.Microsecond
+
+	// The growth factor between two consecutive latency buckets.
+	// Reported percentiles have a relative error of at most 5%.
+	statsLatencyGrowth = 1.05
+
+	// The amount of latency buckets, covering latencies from 100µs up to roughly 60s.
+	statsLatencyBucketCount = 275
+)
+
+// The sliding windows for which latency percentiles are reported.
+var statsWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{Name: "1m", Duration: 1 * time.Minute},
+	{Name: "5m", Duration: 5 * time.Minute},
+	{Name: "15m", Duration: 15 * time.Minute},
+}
+
+// Description:
+//
+//	Tracks statistics about a server upstream.
+//	All methods are safe for concurrent use.
+type ReverseProxyServerUpstreamStats struct {
+	mutex         sync.Mutex
+	requests      uint64
+	inFlight      int64
+	responses     map[string]uint64
+	errors        ReverseProxyServerUpstreamErrorStats
+	bytesSent     uint64
+	bytesReceived uint64
+	webSockets    ReverseProxyServerUpstreamWebSocketStats
+	pool          ReverseProxyServerUpstreamPoolStats
+	slots         [statsSlotCount]statsSlot
+}
+
+// Description:
+//
+//	Holds the error counts of an upstream, grouped by error class.
+type ReverseProxyServerUpstreamErrorStats struct {
+	Client    uint64 `json:"client"`    // The amount of 4xx responses.
+	Server    uint64 `json:"server"`    // The amount of 5xx responses.
+	Transport uint64 `json:"transport"` // The amount of requests which failed without a response, e.g. connection errors.
+}
+
+// Description:
+//
+//	Holds the websocket connection counts of an upstream.
+type ReverseProxyServerUpstreamWebSocketStats struct {
+	Active int64  `json:"active"` // The amount of websocket connections currently open, including pending handshakes.
+	Total  uint64 `json:"total"`  // The total amount of upgraded websocket connections.
+}
+
+// Description:
+//
+//	Holds the connection pool usage of an upstream.
+type ReverseProxyServerUpstreamPoolStats struct {
+	Open   int64  `json:"open"`   // The amount of connections currently open, including idle connections.
+	Dialed uint64 `json:"dialed"` // The total amount of established connections.
+	Failed uint64 `json:"failed"` // The total amount of failed connection attempts.
+	Reused uint64 `json:"reused"` // The total amount of requests sent over a reused connection.
+}
+
+// Description:
+//
+//	Holds the latency statistics of an upstream over a single sliding window.
+//	All latencies are given in milliseconds.
+type ReverseProxyServerUpstreamLatencyStats struct {
+	Count uint64  `json:"count"` // The amount of requests within the window.
+	Mean  float64 `json:"mean"`  // The mean latency.
+	P50   float64 `json:"p50"`   // The 50th percentile latency.
+	P90   float64 `json:"p90"`   // The 90th percentile latency.
+	P99   float64 `json:"p99"`   // The 99th percentile latency.
+}
+
+// Description:
+//
+//	A point in time snapshot of the upstream statistics.
+//	This is the representation used by the inspect api.
+type ReverseProxyServerUpstreamStatsSnapshot struct {
+	Requests      uint64                                            `json:"requests"`      // The total amount of requests.
+	InFlight      int64                                             `json:"inFlight"`      // The amount of requests currently in flight.
+	Responses     map[string]uint64                                 `json:"responses"`     // The amount of responses by status class, e.g. 2xx.
+	Errors        ReverseProxyServerUpstreamErrorStats              `json:"errors"`        // The error counts by class.
+	BytesSent     uint64                                            `json:"bytesSent"`     // The amount of request body bytes sent to the upstream.
+	BytesReceived uint64                                            `json:"bytesReceived"` // The amount of response body bytes received from the upstream.
+	Latency       map[string]ReverseProxyServerUpstreamLatencyStats `json:"latency"`       // The latency statistics by sliding window.
+	WebSockets    ReverseProxyServerUpstreamWebSocketStats          `json:"webSockets"`    // The websocket connection counts.
+	Pool          ReverseProxyServerUpstreamPoolStats               `json:"pool"`          // The connection pool usage.
+}
+
+// Description:
+//
+//	A single time slot of the sliding window.
+//	Latencies are recorded into a logarithmic histogram.
+type statsSlot struct {
+	index   int64
+	count   uint64
+	sum     time.Duration
+	buckets [statsLatencyBucketCount]uint32
+}
+
+// Description:
+//
+//	Creates a new, empty upstream statistics tracker.
+//
+// Returns:
+//
+//	The created statistics tracker.
+func NewReverseProxyServerUpstreamStats() *ReverseProxyServerUpstreamStats {
+	return &ReverseProxyServerUpstreamStats{
+		responses: make(map[string]uint64),
+	}
+}
+
+// Description:
+//
+//	Marks the start of a request to the upstream.
+func (stats *ReverseProxyServerUpstreamStats) Begin() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.requests++
+	stats.inFlight++
+}
+
+// Description:
+//
+//	Marks the end of a request to the upstream.
+//	Must be called exactly once for each call to Begin.
+func (stats *ReverseProxyServerUpstreamStats) End() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.inFlight--
+}
+
+// Description:
+//
+//	Records the outcome of a request to the upstream.
+//
+// Parameters:
+//
+//	statusCode 	The response status code, or 0 if no response was received.
+//	latency 	The time until the response headers were received.
+func (stats *ReverseProxyServerUpstreamStats) RecordResponse(statusCode int, latency time.Duration) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	switch {
+	case statusCode == 0:
+		stats.errors.Transport++
+	case statusCode >= 500:
+		stats.errors.Server++
+	case statusCode >= 400:
+		stats.errors.Client++
+	}
+
+	if statusCode != 0 {
+		stats.responses[statusClass(statusCode)]++
+	}
+
+	stats.slot(time.Now()).record(latency)
+}
+
+// Description:
+//
+//	Records the outcome of a connection attempt to a stream upstream.
+//
+// Parameters:
+//
+//	connected 	Whether the connection has been established.
+//	latency 	The time until the connection was established or failed.
+func (stats *ReverseProxyServerUpstreamStats) RecordConnection(connected bool, latency time.Duration) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	if !connected {
+		stats.errors.Transport++
+	}
+
+	stats.slot(time.Now()).record(latency)
+}
+
+// Description:
+//
+//	Adds the given amount of bytes to the sent bytes counter.
+//
+// Parameters:
+//
+//	count The amount of bytes sent.
+func (stats *ReverseProxyServerUpstreamStats) AddBytesSent(count int) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.bytesSent += uint64(count)
+}
+
+// Description:
+//
+//	Adds the given amount of bytes to the received bytes counter.
+//
+// Parameters:
+//
+//	count The amount of bytes received.
+func (stats *ReverseProxyServerUpstreamStats) AddBytesReceived(count int) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.bytesReceived += uint64(count)
+}
+
+// Description:
+//
+//	Marks the start of a websocket connection to the upstream, i.e. its handshake.
+func (stats *ReverseProxyServerUpstreamStats) BeginWebSocket() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.webSockets.Active++
+}
+
+// Description:
+//
+//	Marks a websocket connection to the upstream as upgraded.
+func (stats *ReverseProxyServerUpstreamStats) UpgradeWebSocket() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.webSockets.Total++
+}
+
+// Description:
+//
+//	Marks the end of a websocket connection to the upstream.
+//	Must be called exactly once for each call to BeginWebSocket.
+func (stats *ReverseProxyServerUpstreamStats) EndWebSocket() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.webSockets.Active--
+}
+
+// Description:
+//
+//	Gets the amount of websocket connections currently open.
+//
+// Returns:
+//
+//	The amount of open websocket connections.
+func (stats *ReverseProxyServerUpstreamStats) WebSockets() int64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	return stats.webSockets.Active
+}
+
+// Description:
+//
+//	Records a connection attempt of the upstream connection pool.
+//
+// Parameters:
+//
+//	connected Whether the connection has been established.
+func (stats *ReverseProxyServerUpstreamStats) RecordDial(connected bool) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	if !connected {
+		stats.pool.Failed++
+		return
+	}
+
+	stats.pool.Dialed++
+	stats.pool.Open++
+}
+
+// Description:
+//
+//	Records the closing of a connection of the upstream connection pool.
+//	Must be called exactly once for each established connection.
+func (stats *ReverseProxyServerUpstreamStats) RecordClose() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.pool.Open--
+}
+
+// Description:
+//
+//	Records a request sent over a reused connection of the upstream connection pool.
+func (stats *ReverseProxyServerUpstreamStats) RecordReuse() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.pool.Reused++
+}
+
+// Description:
+//
+//	Gets the amount of requests currently in flight.
+//
+// Returns:
+//
+//	The amount of in-flight requests.
+func (stats *ReverseProxyServerUpstreamStats) InFlight() int64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	return stats.inFlight
+}
+
+// Description:
+//
+//	Creates a point in time snapshot of the statistics.
+//
+// Returns:
+//
+//	The statistics snapshot.
+func (stats *ReverseProxyServerUpstreamStats) Snapshot() ReverseProxyServerUpstreamStatsSnapshot {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	snapshot := ReverseProxyServerUpstreamStatsSnapshot{
+		Requests:      stats.requests,
+		InFlight:      stats.inFlight,
+		Responses:     make(map[string]uint64),
+		Errors:        stats.errors,
+		BytesSent:     stats.bytesSent,
+		BytesReceived: stats.bytesReceived,
+		Latency:       make(map[string]ReverseProxyServerUpstreamLatencyStats),
+		WebSockets:    stats.webSockets,
+		Pool:          stats.pool,
+	}
+
+	for class, count := range stats.responses {
+		snapshot.Responses[class] = count
+	}
+
+	now := time.Now()
+
+	for _, window := range statsWindows {
+		snapshot.Latency[window.Name] = stats.latency(now, window.Duration)
+	}
+
+	return snapshot
+}
+
+// Description:
+//
+//	Marshals the statistics as a snapshot.
+//
+// Returns:
+//
+//	The json representation of the statistics.
+func (stats *ReverseProxyServerUpstreamStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stats.Snapshot())
+}
+
+// Description:
+//
+//	Gets the slot for the given point in time.
+//	Stale slots are reset before they are returned.
+//
+// Parameters:
+//
+//	now The point in time.
+//
+// Returns:
+//
+//	The slot for the given point in time.
+func (stats *ReverseProxyServerUpstreamStats) slot(now time.Time) *statsSlot {
+	index := now.UnixNano() / int64(statsSlotDuration)
+	slot := &stats.slots[index%statsSlotCount]
+
+	if slot.index != index {
+		*slot = statsSlot{index: index}
+	}
+
+	return slot
+}
+
+// Description:
+//
+//	Computes the latency statistics over a sliding window.
+//
+// Parameters:
+//
+//	now 	The end of the sliding window.
+//	window 	The duration of the sliding window.
+//
+// Returns:
+//
+//	The latency statistics.
+func (stats *ReverseProxyServerUpstreamStats) latency(now time.Time, window time.Duration) ReverseProxyServerUpstreamLatencyStats {
+	current := now.UnixNano() / int64(statsSlotDuration)
+	oldest := current - int64(window/statsSlotDuration) + 1
+
+	result := ReverseProxyServerUpstreamLatencyStats{}
+	buckets := [statsLatencyBucketCount]uint64{}
+	sum := time.Duration(0)
+
+	for index := range stats.slots {
+		slot := &stats.slots[index]
+
+		if slot.index < oldest || slot.index > current {
+			continue
+		}
+
+		result.Count += slot.count
+		sum += slot.sum
+
+		for bucket, count := range slot.buckets {
+			buckets[bucket] += uint64(count)
+		}
+	}
+
+	if result.Count == 0 {
+		return result
+	}
+
+	result.Mean = milliseconds(sum / time.Duration(result.Count))
+	result.P50 = percentile(&buckets, result.Count, 0.50)
+	result.P90 = percentile(&buckets, result.Count, 0.90)
+	result.P99 = percentile(&buckets, result.Count, 0.99)
+
+	return result
+}
+
+// Description:
+//
+//	Records a latency in this slot.
+//
+// Parameters:
+//
+//	latency The latency to record.
+func (slot *statsSlot) record(latency time.Duration) {
+	slot.count++
+	slot.sum += latency
+	slot.buckets[latencyBucket(latency)]++
+}
+
+// Description:
+//
+//	Gets the histogram bucket for the given latency.
+//
+// Parameters:
+//
+//	latency The latency.
+//
+// Returns:
+//
+//	The bucket index.
+func latencyBucket(latency time.Duration) int {
+	if latency <= statsLatencyMin {
+		return 0
+	}
+
+	bucket := int(math.Ceil(math.Log(float64(latency)/float64(statsLatencyMin)) / math.Log(statsLatencyGrowth)))
+
+	if bucket >= statsLatencyBucketCount {
+		return statsLatencyBucketCount - 1
+	}
+
+	return bucket
+}
+
+// Description:
+//
+//	Gets the upper bound of the given histogram bucket.
+//
+// Parameters:
+//
+//	bucket The bucket index.
+//
+// Returns:
+//
+//	The upper bound latency of the bucket.
+func latencyBucketBound(bucket int) time.Duration {
+	return time.Duration(float64(statsLatencyMin) * math.Pow(statsLatencyGrowth, float64(bucket)))
+}
+
+// Description:
+//
+//	Computes a percentile from a latency histogram.
+//
+// Parameters:
+//
+//	buckets 	The histogram buckets.
+//	count 		The total amount of recorded latencies.
+//	quantile 	The quantile to compute, e.g. 0.99.
+//
+// Returns:
+//
+//	The percentile latency in milliseconds.
+func percentile(buckets *[statsLatencyBucketCount]uint64, count uint64, quantile float64) float64 {
+	rank := uint64(math.Ceil(quantile * float64(count)))
+	cumulative := uint64(0)
+
+	for bucket, bucketCount := range buckets {
+		cumulative += bucketCount
+
+		if cumulative >= rank {
+			return milliseconds(latencyBucketBound(bucket))
+		}
+	}
+
+	return milliseconds(latencyBucketBound(statsLatencyBucketCount - 1))
+}
+
+// Description:
+//
+//	Converts a duration to fractional milliseconds.
+//
+// Parameters:
+//
+//	duration The duration to convert.
+//
+// Returns:
+//
+//	The duration in milliseconds.
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// Description:
+//
+//	Gets the status class of a status code, e.g. 2xx.
+//
+// Parameters:
+//
+//	statusCode The status code.
+//
+// Returns:
+//
+//	The status class.
+func statusClass(statusCode int) string {
+	return string(rune('0'+statusCode/100)) + "xx"
+}
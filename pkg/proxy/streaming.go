@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// The upstream response header disabling response buffering for a single response.
+const headerAccelBuffering string = "X-Accel-Buffering"
+
+// Description:
+//
+//	Gets the interval in which response data is flushed to the client.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The flush interval. A negative interval flushes after every write.
+func flushInterval(conf config.ConfigReverseProxyServer) time.Duration {
+	if conf.Streaming.Buffering != nil && !*conf.Streaming.Buffering {
+		return -1
+	}
+
+	if conf.Streaming.FlushInterval < 0 {
+		return -1
+	}
+
+	return time.Duration(conf.Streaming.FlushInterval) * time.Millisecond
+}
+
+// Description:
+//
+//	Disables response buffering, if the upstream asks for it with X-Accel-Buffering: no.
+//	The reverse proxy flushes responses of unknown length after every write,
+//	the same way it flushes text/event-stream responses.
+//
+// Parameters:
+//
+//	response The upstream response.
+func applyResponseBuffering(response *http.Response) {
+	value := response.Header.Get(headerAccelBuffering)
+
+	if value == "" {
+		return
+	}
+
+	response.Header.Del(headerAccelBuffering)
+
+	if strings.EqualFold(value, "no") {
+		response.ContentLength = -1
+	}
+}
+
+// Description:
+//
+//	Modifies an upstream response before it is passed to the client.
+//	Used as response modifier of every upstream reverse proxy.
+//
+// Parameters:
+//
+//	response The upstream response.
+//
+// Returns:
+//
+//	Always nil.
+func modifyUpstreamResponse(response *http.Response) error {
+	removeUpstreamRequestId(response)
+	applyResponseBuffering(response)
+
+	return nil
+}
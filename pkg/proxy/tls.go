@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Description:
+//
+//	Creates the TLS configuration used to connect to the upstreams of a server.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The TLS configuration, or an error, if a certificate cannot be loaded.
+func upstreamTlsConfig(conf config.ConfigReverseProxyServer) (*tls.Config, error) {
+	upstreamTls := conf.UpstreamTls
+
+	minVersion, err := tlsVersion(upstreamTls.MinVersion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &tls.Config{
+		ServerName:         upstreamTls.ServerName,
+		MinVersion:         minVersion,
+		InsecureSkipVerify: upstreamTls.InsecureSkipVerify,
+	}
+
+	if upstreamTls.InsecureSkipVerify {
+		log.Warnf("proxy: upstream certificates are not verified: %s", conf.Name)
+	}
+
+	if upstreamTls.CaFile != "" {
+		bundle, err := os.ReadFile(upstreamTls.CaFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("proxy: unable to read ca file: %s", err)
+		}
+
+		result.RootCAs = x509.NewCertPool()
+
+		if !result.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("proxy: no certificates found in ca file: %s", upstreamTls.CaFile)
+		}
+	}
+
+	if upstreamTls.CertFile != "" || upstreamTls.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(upstreamTls.CertFile, upstreamTls.KeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("proxy: unable to load client certificate: %s", err)
+		}
+
+		result.Certificates = []tls.Certificate{certificate}
+	}
+
+	return result, nil
+}
+
+// Description:
+//
+//	Parses a TLS version.
+//
+// Parameters:
+//
+//	version The TLS version, e.g. 1.2. If empty, TLS 1.2 is used.
+//
+// Returns:
+//
+//	The TLS version constant, or an error, if the version is not supported.
+func tlsVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("proxy: unsupported tls version: %s", version)
+}
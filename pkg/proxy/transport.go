@@ -1,31 +1,179 @@
 package proxy
 
 import (
+	"io"
 	"net/http"
+	"net/http/httptrace"
+	"sync"
 	"time"
 
-	"github.com/revx-official/output/log"
+	"github.com/revx-official/revx/pkg/tracing"
 )
 
+// Description:
+//
+//	The round tripper used by every reverse proxy upstream.
+//	Wraps the actual transport and tracks the upstream statistics.
 type ReverseProxyTransport struct {
 	ProxyInstance *ReverseProxyServerUpstreamInfo
 	Transport     http.RoundTripper
 }
 
+// Description:
+//
+//	Creates a new reverse proxy transport.
+//
+// Parameters:
+//
+//	proxy 		The upstream the transport belongs to.
+//	transport 	The underlying transport.
+//
+// Returns:
+//
+//	The created reverse proxy transport.
 func NewReverseProxyTransport(proxy *ReverseProxyServerUpstreamInfo, transport http.RoundTripper) ReverseProxyTransport {
 	return ReverseProxyTransport{ProxyInstance: proxy, Transport: transport}
 }
 
+// Description:
+//
+//	Performs a single round trip to the upstream.
+//	A request is considered in flight until its response body is closed.
+//	If the request is traced, a client span is created for the attempt and propagated to the upstream.
+//
+// Parameters:
+//
+//	request The request to send.
+//
+// Returns:
+//
+//	The upstream response, or an error.
 func (transport ReverseProxyTransport) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	stats := transport.ProxyInstance.Stats
+	stats.Begin()
+
+	if request.Body != nil && request.Body != http.NoBody {
+		request.Body = &countingReadCloser{ReadCloser: request.Body, count: stats.AddBytesSent}
+	}
+
+	span := tracing.StartSpan(request.Context(), request.Method, tracing.SpanKindClient)
+	defer span.Finish()
+
+	if span != nil {
+		span.Context.Inject(request.Header)
+		span.SetAttribute("http.request.method", request.Method)
+		span.SetAttribute("url.full", request.URL.String())
+		span.SetAttribute("server.address", request.URL.Host)
+	}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				stats.RecordReuse()
+			}
+		},
+	}
+
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
 	start := time.Now()
 
 	response, err = transport.Transport.RoundTrip(request)
 	duration := time.Since(start)
 
-	log.Tracef("proxy: pass info: %s %s", request.Method, request.URL, duration)
+	log.Tracef("proxy: pass info: %s %s %s%s", request.Method, request.URL, duration, RequestIdSuffix(request))
+
+	if err != nil {
+		span.SetError(err.Error())
+		stats.RecordResponse(0, duration)
+		stats.End()
+
+		return response, err
+	}
 
-	milliseconds := float32(duration * time.Millisecond)
-	transport.ProxyInstance.Stats.AverageRequestTime = (transport.ProxyInstance.Stats.AverageRequestTime + milliseconds) / 2.0
+	span.SetAttribute("http.response.status_code", response.StatusCode)
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		span.SetError(http.StatusText(response.StatusCode))
+	}
+
+	stats.RecordResponse(response.StatusCode, duration)
+	response.Body = newCountingResponseBody(response.Body, stats)
 
 	return response, err
 }
+
+// Description:
+//
+//	Wraps a response body, counting all read bytes.
+//	Marks the request as finished once the body is closed.
+//
+// Parameters:
+//
+//	body 	The response body.
+//	stats 	The upstream statistics.
+//
+// Returns:
+//
+//	The wrapped response body.
+func newCountingResponseBody(body io.ReadCloser, stats *ReverseProxyServerUpstreamStats) io.ReadCloser {
+	counting := &countingReadCloser{ReadCloser: body, count: stats.AddBytesReceived, close: stats.End}
+
+	// Upgraded connections, e.g. websockets, require a writable body.
+	if writer, ok := body.(io.ReadWriteCloser); ok {
+		return &countingReadWriteCloser{countingReadCloser: counting, writer: writer}
+	}
+
+	return counting
+}
+
+// Description:
+//
+//	A read closer which reports the amount of read bytes.
+type countingReadCloser struct {
+	io.ReadCloser
+	count func(int)
+	close func()
+	once  sync.Once
+}
+
+// Description:
+//
+//	Reads from the underlying reader and reports the amount of read bytes.
+func (reader *countingReadCloser) Read(buffer []byte) (int, error) {
+	count, err := reader.ReadCloser.Read(buffer)
+
+	if count > 0 {
+		reader.count(count)
+	}
+
+	return count, err
+}
+
+// Description:
+//
+//	Closes the underlying reader.
+//	The close callback is invoked at most once.
+func (reader *countingReadCloser) Close() error {
+	err := reader.ReadCloser.Close()
+
+	if reader.close != nil {
+		reader.once.Do(reader.close)
+	}
+
+	return err
+}
+
+// Description:
+//
+//	A read write closer which reports the amount of read bytes.
+type countingReadWriteCloser struct {
+	*countingReadCloser
+	writer io.Writer
+}
+
+// Description:
+//
+//	Writes to the underlying writer.
+func (reader *countingReadWriteCloser) Write(buffer []byte) (int, error) {
+	return reader.writer.Write(buffer)
+}
@@ -0,0 +1,434 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Constant declarations.
+const (
+	// The close code sent when a websocket is closed due to inactivity or a shutdown.
+	webSocketCloseGoingAway uint16 = 1001
+
+	// The close code sent when a client message exceeds the maximum message size.
+	webSocketCloseMessageTooBig uint16 = 1009
+
+	// The time granted to both peers to complete the closing handshake,
+	// before the connection is closed forcibly.
+	webSocketCloseTimeout = 5 * time.Second
+)
+
+// The error returned to the proxy once a client message exceeded the maximum message size.
+var errWebSocketMessageTooBig = errors.New("proxy: websocket message too big")
+
+// All open websockets, closed gracefully on shutdown.
+var webSockets = map[*webSocketConn]struct{}{}
+
+// The mutex used to control access to the open websockets.
+var webSocketsMutex = sync.Mutex{}
+
+// Description:
+//
+//	Checks whether a request asks for a websocket upgrade.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	True, if the request is a websocket upgrade.
+func IsWebSocketRequest(request *http.Request) bool {
+	if !strings.EqualFold(request.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+
+	for _, value := range request.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Gracefully closes all open websockets.
+//	Sends a close frame to every client and closes the connections forcibly,
+//	if the closing handshake does not complete in time.
+func CloseWebSockets() {
+	webSocketsMutex.Lock()
+	defer webSocketsMutex.Unlock()
+
+	if len(webSockets) > 0 {
+		log.Infof("proxy: closing %d websockets ...", len(webSockets))
+	}
+
+	for conn := range webSockets {
+		conn.closeGracefully(webSocketCloseGoingAway, "shutting down")
+	}
+}
+
+// Description:
+//
+//	Passes a websocket upgrade to an upstream.
+//	Blocks until the websocket is closed.
+//
+// Parameters:
+//
+//	prox 		The reverse proxy.
+//	instance 	The upstream.
+//	response 	The response writer.
+//	request 	The request.
+func serveWebSocket(prox *ReverseProxyServerInfo, instance *ReverseProxyServerUpstreamInfo, response http.ResponseWriter, request *http.Request) {
+	writer := &webSocketResponseWriter{ResponseWriter: response, info: prox.WebSocketInfo, stats: instance.Stats}
+	defer writer.release()
+
+	instance.ReverseProxy.ServeHTTP(writer, request)
+}
+
+// Description:
+//
+//	A response writer, which wraps the client connection once it is hijacked for a websocket.
+type webSocketResponseWriter struct {
+	http.ResponseWriter
+	info  ReverseProxyServerWebSocketInfo
+	stats *ReverseProxyServerUpstreamStats
+	conn  *webSocketConn
+}
+
+// Description:
+//
+//	Hijacks the client connection and wraps it.
+//
+// Returns:
+//
+//	The wrapped client connection, its buffered reader and writer, or an error.
+func (writer *webSocketResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buffer, err := http.NewResponseController(writer.ResponseWriter).Hijack()
+
+	if err != nil {
+		return conn, buffer, err
+	}
+
+	writer.stats.UpgradeWebSocket()
+	writer.conn = newWebSocketConn(conn, writer.info)
+
+	return writer.conn, buffer, nil
+}
+
+// Description:
+//
+//	Gets the wrapped response writer.
+//
+// Returns:
+//
+//	The wrapped response writer.
+func (writer *webSocketResponseWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// Description:
+//
+//	Releases the wrapped client connection, if the connection has been hijacked.
+func (writer *webSocketResponseWriter) release() {
+	if writer.conn != nil {
+		writer.conn.release()
+	}
+}
+
+// Description:
+//
+//	A client websocket connection.
+//	Tracks the frames in both directions to enforce the maximum message size,
+//	to close idle websockets and to send close frames at frame boundaries.
+type webSocketConn struct {
+	net.Conn
+	info    ReverseProxyServerWebSocketInfo
+	reader  webSocketFrameParser
+	writer  webSocketFrameParser
+	mutex   sync.Mutex
+	idle    *time.Timer
+	closing atomic.Bool
+	err     error
+}
+
+// Description:
+//
+//	Wraps a client websocket connection and registers it for a graceful shutdown.
+//
+// Parameters:
+//
+//	conn The hijacked client connection.
+//	info The websocket information of the proxy.
+//
+// Returns:
+//
+//	The wrapped connection.
+func newWebSocketConn(conn net.Conn, info ReverseProxyServerWebSocketInfo) *webSocketConn {
+	wrapped := &webSocketConn{Conn: conn, info: info}
+
+	if info.IdleTimeout > 0 {
+		wrapped.idle = time.AfterFunc(time.Duration(info.IdleTimeout)*time.Millisecond, func() {
+			wrapped.closeGracefully(webSocketCloseGoingAway, "idle timeout")
+		})
+	}
+
+	webSocketsMutex.Lock()
+	webSockets[wrapped] = struct{}{}
+	webSocketsMutex.Unlock()
+
+	return wrapped
+}
+
+// Description:
+//
+//	Reads data sent by the client.
+//	Stops at the frame exceeding the maximum message size and closes the websocket.
+func (conn *webSocketConn) Read(buffer []byte) (int, error) {
+	if conn.err != nil {
+		return 0, conn.err
+	}
+
+	count, err := conn.Conn.Read(buffer)
+	conn.touch()
+
+	if count == 0 {
+		return count, err
+	}
+
+	accepted, exceeded := conn.reader.process(buffer[:count], uint64(conn.info.MaxMessageSize))
+
+	if exceeded {
+		log.Debugf("proxy: closing websocket: message exceeds %d bytes: %s", conn.info.MaxMessageSize, conn.RemoteAddr())
+		conn.closeGracefully(webSocketCloseMessageTooBig, "message too big")
+		conn.err = errWebSocketMessageTooBig
+
+		if accepted == 0 {
+			return 0, conn.err
+		}
+
+		return accepted, nil
+	}
+
+	return count, err
+}
+
+// Description:
+//
+//	Writes data sent by the upstream to the client.
+func (conn *webSocketConn) Write(buffer []byte) (int, error) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	count, err := conn.Conn.Write(buffer)
+	conn.writer.process(buffer[:count], 0)
+	conn.touch()
+
+	return count, err
+}
+
+// Description:
+//
+//	Resets the idle timeout.
+func (conn *webSocketConn) touch() {
+	if conn.idle != nil && !conn.closing.Load() {
+		conn.idle.Reset(time.Duration(conn.info.IdleTimeout) * time.Millisecond)
+	}
+}
+
+// Description:
+//
+//	Sends a close frame to the client and closes the connection forcibly,
+//	if the closing handshake does not complete in time.
+//	The close frame is only sent at a frame boundary, otherwise the connection is closed right away.
+//
+// Parameters:
+//
+//	code 	The close code.
+//	reason 	The close reason.
+func (conn *webSocketConn) closeGracefully(code uint16, reason string) {
+	if conn.closing.Swap(true) {
+		return
+	}
+
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	if !conn.writer.atBoundary() {
+		conn.Conn.Close()
+		return
+	}
+
+	frame := make([]byte, 4, 4+len(reason))
+	frame[0] = 0x88
+	frame[1] = byte(2 + len(reason))
+	binary.BigEndian.PutUint16(frame[2:], code)
+	frame = append(frame, reason...)
+
+	conn.Conn.SetWriteDeadline(time.Now().Add(webSocketCloseTimeout))
+	conn.Conn.Write(frame)
+	conn.Conn.SetDeadline(time.Now().Add(webSocketCloseTimeout))
+}
+
+// Description:
+//
+//	Unregisters the connection, once the websocket is closed.
+func (conn *webSocketConn) release() {
+	if conn.idle != nil {
+		conn.idle.Stop()
+	}
+
+	webSocketsMutex.Lock()
+	delete(webSockets, conn)
+	webSocketsMutex.Unlock()
+}
+
+// Description:
+//
+//	An incremental websocket frame parser for one direction of a connection.
+//	Only frame headers are inspected, payloads are skipped.
+type webSocketFrameParser struct {
+	header    [14]byte
+	length    int
+	remaining uint64
+	message   uint64
+}
+
+// Description:
+//
+//	Processes the next chunk of a websocket stream.
+//
+// Parameters:
+//
+//	buffer 	The chunk.
+//	limit 	The maximum message size in bytes, or 0 to disable the limit.
+//
+// Returns:
+//
+//	The amount of bytes before the frame exceeding the limit, and whether the limit was exceeded.
+func (parser *webSocketFrameParser) process(buffer []byte, limit uint64) (int, bool) {
+	index := 0
+
+	for index < len(buffer) {
+		if parser.remaining > 0 {
+			skip := uint64(len(buffer) - index)
+
+			if skip > parser.remaining {
+				skip = parser.remaining
+			}
+
+			parser.remaining -= skip
+			index += int(skip)
+
+			continue
+		}
+
+		parser.header[parser.length] = buffer[index]
+		parser.length++
+		index++
+
+		if parser.length < 2 || parser.length < parser.headerSize() {
+			continue
+		}
+
+		start := index - parser.length
+
+		if start < 0 {
+			start = 0
+		}
+
+		fin := parser.header[0]&0x80 != 0
+		opcode := parser.header[0] & 0x0f
+		payload := parser.payloadLength()
+
+		parser.length = 0
+		parser.remaining = payload
+
+		// Control frames may be interleaved with message fragments and do not count.
+		if opcode >= 0x8 {
+			continue
+		}
+
+		if opcode != 0x0 {
+			parser.message = 0
+		}
+
+		parser.message += payload
+
+		if limit > 0 && parser.message > limit {
+			return start, true
+		}
+
+		if fin {
+			parser.message = 0
+		}
+	}
+
+	return len(buffer), false
+}
+
+// Description:
+//
+//	Gets the size of the current frame header.
+//	Requires the first two header bytes.
+//
+// Returns:
+//
+//	The size of the frame header in bytes.
+func (parser *webSocketFrameParser) headerSize() int {
+	size := 2
+
+	switch parser.header[1] & 0x7f {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+
+	if parser.header[1]&0x80 != 0 {
+		size += 4
+	}
+
+	return size
+}
+
+// Description:
+//
+//	Gets the payload length of the current frame.
+//	Requires the complete frame header.
+//
+// Returns:
+//
+//	The payload length in bytes.
+func (parser *webSocketFrameParser) payloadLength() uint64 {
+	switch length := parser.header[1] & 0x7f; length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(parser.header[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(parser.header[2:10])
+	default:
+		return uint64(length)
+	}
+}
+
+// Description:
+//
+//	Checks whether the parser is located between two frames.
+//
+// Returns:
+//
+//	True, if the parser is located between two frames.
+func (parser *webSocketFrameParser) atBoundary() bool {
+	return parser.length == 0 && parser.remaining == 0
+}
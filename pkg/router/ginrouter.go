@@ -1,10 +1,15 @@
 package router
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +19,10 @@ import (
 //	Implementation of the Router interface for gin.
 type GinRouter struct {
 	engine *gin.Engine
+	server *http.Server
+	active sync.WaitGroup
+	cancel context.CancelFunc
+	mutex  sync.Mutex
 }
 
 // Description:
@@ -71,6 +80,9 @@ func (router *GinRouter) Handle(method string, path string, handler RouterHandle
 //	handler	The handler responsible for handling the request.
 func (router *GinRouter) ProxyHandle(method string, path string, handler RouterProxyHandlerFunc) {
 	router.engine.Handle(method, path, func(context *gin.Context) {
+		router.active.Add(1)
+		defer router.active.Done()
+
 		internalProxyRouteHandler(context, handler)
 	})
 }
@@ -79,24 +91,147 @@ func (router *GinRouter) ProxyHandle(method string, path string, handler RouterP
 //
 //	Starts the HTTP server for this router and listens to all registered routes.
 //
+// Parameters:
+//
+//	port 	The port to listen on.
+//	options The server options.
+//
 // Returns:
 //
 //	An error if serving the router fails.
-func (router *GinRouter) Run(port uint16) error {
+func (router *GinRouter) Run(port uint16, options RouterServerOptions) error {
 	portFmt := fmt.Sprintf(":%d", port)
+	listener, err := net.Listen("tcp", portFmt)
+
+	if err != nil {
+		return err
+	}
+
+	return router.Serve(listener, options)
+}
+
+// Description:
+//
+//	Starts the HTTP server for this router on an existing listener.
+//	Blocks until the server is shut down.
+//
+// Parameters:
+//
+//	listener 	The listener to accept connections on.
+//	options 	The server options.
+//
+// Returns:
+//
+//	An error if serving the router fails.
+func (router *GinRouter) Serve(listener net.Listener, options RouterServerOptions) error {
+	baseContext, cancel := context.WithCancel(context.Background())
+
+	router.engine.UseH2C = options.H2c
+
+	server := &http.Server{
+		Handler:   router.engine.Handler(),
+		TLSConfig: options.TlsConfig,
+		BaseContext: func(net.Listener) context.Context {
+			return baseContext
+		},
+	}
+
+	// A non-nil map prevents the server from registering HTTP/2 for TLS connections.
+	if !options.Http2 {
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	// Setting this to false apparently reduces memory usage.
+	// However, setting this to true apparently is the standard and improves performance.
+	server.SetKeepAlivesEnabled(true)
+
+	router.mutex.Lock()
+	router.server = server
+	router.cancel = cancel
+	router.mutex.Unlock()
+
+	var err error
+
+	if options.TlsConfig != nil {
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
 
-	// server := &http.Server{
-	// 	Addr:    portFmt,
-	// 	Handler: router.engine,
-	// }
+// Description:
+//
+//	Gracefully shuts down the HTTP server.
+//	Stops accepting connections and waits for all active requests to complete,
+//	including upgraded connections, e.g. websockets.
+//	If the context expires first, all remaining requests are cancelled and their connections closed.
+//
+// Parameters:
+//
+//	ctx The context limiting the time to drain active requests.
+//
+// Returns:
+//
+//	An error, if the context expired before all requests completed.
+func (router *GinRouter) Shutdown(ctx context.Context) error {
+	router.mutex.Lock()
+	server := router.server
+	cancel := router.cancel
+	router.mutex.Unlock()
 
-	// // Setting this to false apparently reduces memory usage.
-	// // However, setting this to true apparently is the standard and improves performance.
-	// server.SetKeepAlivesEnabled(true)
+	if server == nil {
+		return nil
+	}
 
-	// return server.ListenAndServe()
+	err := server.Shutdown(ctx)
 
-	return router.engine.Run(portFmt)
+	if err == nil {
+		err = waitContext(ctx, &router.active)
+	}
+
+	if err != nil {
+		cancel()
+		server.Close()
+
+		return err
+	}
+
+	cancel()
+	return nil
+}
+
+// Description:
+//
+//	Waits for a wait group, until the given context expires.
+//
+// Parameters:
+//
+//	ctx 	The context limiting the time to wait.
+//	group 	The wait group to wait for.
+//
+// Returns:
+//
+//	An error, if the context expired first.
+func waitContext(ctx context.Context, group *sync.WaitGroup) error {
+	done := make(chan struct{})
+
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Description:
@@ -152,6 +287,7 @@ func transformRequest(pathHandle string, request *http.Request) (*Request, error
 		Method:          request.Method,
 		Url:             request.URL.String(),
 		Path:            request.URL.Path,
+		RemoteAddr:      request.RemoteAddr,
 		Headers:         make(map[string]string),
 		PathParameters:  make(map[string]string),
 		QueryParameters: make(map[string]string),
@@ -174,6 +310,16 @@ func transformRequest(pathHandle string, request *http.Request) (*Request, error
 	}
 
 	result.QueryParameters = queryParameters
+
+	if request.Body != nil {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Body = string(body)
+	}
+
 	return &result, nil
 }
 
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Description:
+//
+//	A response writer which lets a function modify the response header, right before it is written.
+//	Informational responses are passed through unchanged.
+//	Flushing and hijacking are passed through to the wrapped response writer.
+type HeaderInterceptor struct {
+	http.ResponseWriter
+	intercept func(header http.Header, statusCode int)
+	written   bool
+}
+
+// Description:
+//
+//	Creates a new header interceptor.
+//
+// Parameters:
+//
+//	response 	The response writer to wrap.
+//	intercept 	The function modifying the response header, called once with the final status code.
+//
+// Returns:
+//
+//	The header interceptor.
+func NewHeaderInterceptor(response http.ResponseWriter, intercept func(header http.Header, statusCode int)) *HeaderInterceptor {
+	return &HeaderInterceptor{ResponseWriter: response, intercept: intercept}
+}
+
+// Description:
+//
+//	Writes the response header with the given status code.
+//
+// Parameters:
+//
+//	statusCode The response status code.
+func (interceptor *HeaderInterceptor) WriteHeader(statusCode int) {
+	final := statusCode >= 200 || statusCode == http.StatusSwitchingProtocols
+
+	if !interceptor.written && final {
+		interceptor.written = true
+		interceptor.intercept(interceptor.ResponseWriter.Header(), statusCode)
+	}
+
+	interceptor.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Description:
+//
+//	Writes response body bytes.
+//
+// Parameters:
+//
+//	buffer The bytes to write.
+//
+// Returns:
+//
+//	The amount of written bytes, or an error.
+func (interceptor *HeaderInterceptor) Write(buffer []byte) (int, error) {
+	if !interceptor.written {
+		interceptor.WriteHeader(http.StatusOK)
+	}
+
+	return interceptor.ResponseWriter.Write(buffer)
+}
+
+// Description:
+//
+//	Flushes buffered data to the client, if supported by the wrapped response writer.
+func (interceptor *HeaderInterceptor) Flush() {
+	if !interceptor.written {
+		interceptor.WriteHeader(http.StatusOK)
+	}
+
+	if flusher, ok := interceptor.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Description:
+//
+//	Takes over the underlying connection, if supported by the wrapped response writer.
+//	The response header of hijacked connections is not modified.
+//
+// Returns:
+//
+//	The hijacked connection and its buffered reader and writer, or an error.
+func (interceptor *HeaderInterceptor) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := interceptor.ResponseWriter.(http.Hijacker)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("router: response writer does not support hijacking")
+	}
+
+	interceptor.written = true
+	return hijacker.Hijack()
+}
+
+// Description:
+//
+//	Gets the wrapped response writer.
+//	Used by http.ResponseController.
+//
+// Returns:
+//
+//	The wrapped response writer.
+func (interceptor *HeaderInterceptor) Unwrap() http.ResponseWriter {
+	return interceptor.ResponseWriter
+}
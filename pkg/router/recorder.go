@@ -0,0 +1,131 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Description:
+//
+//	A response writer which records the status code and the amount of written body bytes.
+//	Flushing and hijacking are passed through to the wrapped response writer.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	StatusCode int   // The response status code, or 0 if no header has been written yet.
+	Size       int64 // The amount of written body bytes.
+}
+
+// Description:
+//
+//	Creates a new response recorder.
+//	If the given response writer already is a recorder, it is returned as is.
+//
+// Parameters:
+//
+//	response The response writer to wrap.
+//
+// Returns:
+//
+//	The response recorder.
+func NewResponseRecorder(response http.ResponseWriter) *ResponseRecorder {
+	if recorder, ok := response.(*ResponseRecorder); ok {
+		return recorder
+	}
+
+	return &ResponseRecorder{ResponseWriter: response}
+}
+
+// Description:
+//
+//	Writes the response header with the given status code.
+//
+// Parameters:
+//
+//	statusCode The response status code.
+func (recorder *ResponseRecorder) WriteHeader(statusCode int) {
+	if recorder.StatusCode == 0 || recorder.StatusCode < 200 {
+		recorder.StatusCode = statusCode
+	}
+
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Description:
+//
+//	Writes response body bytes.
+//
+// Parameters:
+//
+//	buffer The bytes to write.
+//
+// Returns:
+//
+//	The amount of written bytes, or an error.
+func (recorder *ResponseRecorder) Write(buffer []byte) (int, error) {
+	if recorder.StatusCode == 0 {
+		recorder.StatusCode = http.StatusOK
+	}
+
+	count, err := recorder.ResponseWriter.Write(buffer)
+	recorder.Size += int64(count)
+
+	return count, err
+}
+
+// Description:
+//
+//	Gets the recorded status code.
+//	Defaults to 200 if no header has been written.
+//
+// Returns:
+//
+//	The status code.
+func (recorder *ResponseRecorder) Status() int {
+	if recorder.StatusCode == 0 {
+		return http.StatusOK
+	}
+
+	return recorder.StatusCode
+}
+
+// Description:
+//
+//	Flushes buffered data to the client, if supported by the wrapped response writer.
+func (recorder *ResponseRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Description:
+//
+//	Takes over the underlying connection, if supported by the wrapped response writer.
+//	Hijacked connections are recorded as switching protocols.
+//
+// Returns:
+//
+//	The hijacked connection and its buffered reader and writer, or an error.
+func (recorder *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("router: response writer does not support hijacking")
+	}
+
+	recorder.StatusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Description:
+//
+//	Gets the wrapped response writer.
+//	Used by http.ResponseController.
+//
+// Returns:
+//
+//	The wrapped response writer.
+func (recorder *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -1,6 +1,11 @@
 package router
 
-import "net/http"
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+)
 
 // Description:
 //
@@ -12,6 +17,12 @@ type RouterHandlerFunc = func(request *Request) *Response
 //	Function definition for router endpoint handlers.
 type RouterProxyHandlerFunc = func(request *http.Request, response http.ResponseWriter)
 
+// Description:
+//
+//	Function definition for proxy handler middlewares.
+//	A middleware wraps a proxy handler and returns the wrapping handler.
+type RouterProxyMiddlewareFunc = func(handler RouterProxyHandlerFunc) RouterProxyHandlerFunc
+
 // Description:
 //
 //	A router request.
@@ -20,6 +31,7 @@ type Request struct {
 	Method          string
 	Url             string
 	Path            string
+	RemoteAddr      string
 	Headers         map[string]string `json:"headers"`
 	PathParameters  map[string]string `json:"pathParameters"`
 	QueryParameters map[string]string `json:"queryParameters"`
@@ -36,13 +48,24 @@ type Response struct {
 	Body       interface{}       `json:"body"`
 }
 
+// Description:
+//
+//	The options used to serve a router.
+type RouterServerOptions struct {
+	TlsConfig *tls.Config // The TLS configuration. If nil, connections are served in cleartext.
+	Http2     bool        // Whether to negotiate HTTP/2 on TLS connections.
+	H2c       bool        // Whether to accept HTTP/2 over cleartext connections.
+}
+
 // Description:
 //
 //	The router interface.
 type Router interface {
 	Handle(method string, path string, handler RouterHandlerFunc)
 	ProxyHandle(method string, path string, handler RouterProxyHandlerFunc)
-	Run(port uint16) error
+	Run(port uint16, options RouterServerOptions) error
+	Serve(listener net.Listener, options RouterServerOptions) error
+	Shutdown(ctx context.Context) error
 }
 
 // Description:
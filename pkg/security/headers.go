@@ -0,0 +1,156 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// Constant declarations.
+const (
+	// The preset for applications without inline scripts and framing.
+	PresetStrict string = "strict"
+
+	// The preset for applications, which cannot adopt a content security policy.
+	PresetRelaxed string = "relaxed"
+)
+
+// Description:
+//
+//	A security response header.
+type header struct {
+	name  string
+	value string
+}
+
+// The header values of the presets.
+var presets = map[string]map[string]string{
+	PresetStrict: {
+		"Strict-Transport-Security": "max-age=63072000; includeSubDomains",
+		"Content-Security-Policy":   "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'",
+		"X-Frame-Options":           "DENY",
+		"X-Content-Type-Options":    "nosniff",
+		"Referrer-Policy":           "no-referrer",
+		"Permissions-Policy":        "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+	},
+	PresetRelaxed: {
+		"Strict-Transport-Security": "max-age=31536000",
+		"Content-Security-Policy":   "frame-ancestors 'self'",
+		"X-Frame-Options":           "SAMEORIGIN",
+		"X-Content-Type-Options":    "nosniff",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+	},
+}
+
+// The response headers revealing server software and versions.
+var serverHeaders = []string{
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-AspNetMvc-Version",
+	"X-Generator",
+}
+
+// Description:
+//
+//	Creates a middleware, which adds security headers to all responses of a server,
+//	including error responses of revx.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if the preset does not exist.
+func HeadersMiddleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	securityHeaders := conf.SecurityHeaders
+
+	if !securityHeaders.Enabled {
+		return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+			return handler
+		}, nil
+	}
+
+	headers, err := resolveHeaders(securityHeaders)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			response = router.NewHeaderInterceptor(response, func(header http.Header, statusCode int) {
+				if securityHeaders.StripServerHeaders {
+					for _, name := range serverHeaders {
+						header.Del(name)
+					}
+				}
+
+				for _, security := range headers {
+					if securityHeaders.Override || header.Get(security.name) == "" {
+						header.Set(security.name, security.value)
+					}
+				}
+			})
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Resolves the header values of a configuration.
+//	Configured values take precedence over the values of the preset.
+//
+// Parameters:
+//
+//	conf The security headers configuration.
+//
+// Returns:
+//
+//	The non-empty headers, or an error, if the preset does not exist.
+func resolveHeaders(conf config.ConfigReverseProxyServerSecurityHeaders) ([]header, error) {
+	preset := map[string]string{}
+
+	if conf.Preset != "" {
+		values, exists := presets[conf.Preset]
+
+		if !exists {
+			return nil, fmt.Errorf("security: unknown security headers preset: %s", conf.Preset)
+		}
+
+		preset = values
+	}
+
+	configured := []struct {
+		name  string
+		value *string
+	}{
+		{"Strict-Transport-Security", conf.StrictTransportSecurity},
+		{"Content-Security-Policy", conf.ContentSecurityPolicy},
+		{"X-Frame-Options", conf.FrameOptions},
+		{"X-Content-Type-Options", conf.ContentTypeOptions},
+		{"Referrer-Policy", conf.ReferrerPolicy},
+		{"Permissions-Policy", conf.PermissionsPolicy},
+	}
+
+	headers := []header{}
+
+	for _, entry := range configured {
+		value := preset[entry.name]
+
+		if entry.value != nil {
+			value = *entry.value
+		}
+
+		if value != "" {
+			headers = append(headers, header{name: entry.name, value: value})
+		}
+	}
+
+	return headers, nil
+}
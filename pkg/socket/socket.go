@@ -0,0 +1,233 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/revx-official/revx/pkg/logging"
+)
+
+// The socket subsystem logger.
+var log = logging.NewLogger("socket")
+
+// Description:
+//
+//	A socket, which can be passed to another process as file.
+type filer interface {
+	File() (*os.File, error)
+}
+
+// The listeners inherited from the parent process, which have not been used yet.
+var inherited []net.Listener
+
+// The packet connections inherited from the parent process, which have not been used yet.
+var inheritedPackets []net.PacketConn
+
+// Ensures inherited sockets are only collected once.
+var inheritOnce = sync.Once{}
+
+// All sockets created by this process, passed to a new process during an upgrade.
+var sockets []filer
+
+// The mutex used to control access to the sockets.
+var mutex = sync.Mutex{}
+
+// Description:
+//
+//	Creates a listener on the given port.
+//	Reuses a matching listener inherited from the parent process, if there is one.
+//
+// Parameters:
+//
+//	port The port to listen on.
+//
+// Returns:
+//
+//	The listener, or an error.
+func Listen(port uint16) (net.Listener, error) {
+	return ListenTcp(fmt.Sprintf(":%d", port))
+}
+
+// Description:
+//
+//	Creates a tcp listener on the given address.
+//	Reuses an inherited listener with the same port, if there is one.
+//
+// Parameters:
+//
+//	address The address to listen on, e.g. :5432.
+//
+// Returns:
+//
+//	The listener, or an error.
+func ListenTcp(address string) (net.Listener, error) {
+	port, err := addressPort(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	inherit()
+
+	for index, listener := range inherited {
+		if localPort(listener.Addr()) != port {
+			continue
+		}
+
+		inherited = append(inherited[:index], inherited[index+1:]...)
+		sockets = append(sockets, listener.(filer))
+		log.Infof("socket: using inherited listener: %s", listener.Addr())
+
+		return listener, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sockets = append(sockets, listener.(filer))
+	return listener, nil
+}
+
+// Description:
+//
+//	Creates a udp packet connection on the given address.
+//	Reuses an inherited packet connection with the same port, if there is one.
+//
+// Parameters:
+//
+//	address The address to listen on, e.g. :53.
+//
+// Returns:
+//
+//	The packet connection, or an error.
+func ListenUdp(address string) (net.PacketConn, error) {
+	port, err := addressPort(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	inherit()
+
+	for index, conn := range inheritedPackets {
+		if localPort(conn.LocalAddr()) != port {
+			continue
+		}
+
+		inheritedPackets = append(inheritedPackets[:index], inheritedPackets[index+1:]...)
+		sockets = append(sockets, conn.(filer))
+		log.Infof("socket: using inherited packet connection: %s", conn.LocalAddr())
+
+		return conn, nil
+	}
+
+	conn, err := net.ListenPacket("udp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sockets = append(sockets, conn.(filer))
+	return conn, nil
+}
+
+// Description:
+//
+//	Closes all inherited sockets which have not been used.
+//	Called once all sockets have been created.
+func CloseUnusedListeners() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	inherit()
+
+	for _, listener := range inherited {
+		log.Infof("socket: closing unused inherited listener: %s", listener.Addr())
+		listener.Close()
+	}
+
+	for _, conn := range inheritedPackets {
+		log.Infof("socket: closing unused inherited packet connection: %s", conn.LocalAddr())
+		conn.Close()
+	}
+
+	inherited = nil
+	inheritedPackets = nil
+}
+
+// Description:
+//
+//	Collects the inherited sockets, if not done yet.
+//	Must be called with the mutex held.
+func inherit() {
+	inheritOnce.Do(func() {
+		listeners, packets, err := inheritedSockets()
+
+		if err != nil {
+			log.Warnf("socket: unable to use inherited sockets: %s", err)
+		}
+
+		inherited = listeners
+		inheritedPackets = packets
+	})
+}
+
+// Description:
+//
+//	Gets the port of an address.
+//
+// Parameters:
+//
+//	address The address, e.g. :8080 or [::]:8080.
+//
+// Returns:
+//
+//	The port, or 0 and an error, if the address has no valid port.
+func addressPort(address string) (uint16, error) {
+	_, port, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseUint(port, 10, 16)
+
+	if err != nil {
+		return 0, fmt.Errorf("socket: invalid port: %s", address)
+	}
+
+	return uint16(value), nil
+}
+
+// Description:
+//
+//	Gets the port of a local socket address.
+//
+// Parameters:
+//
+//	address The local socket address.
+//
+// Returns:
+//
+//	The port, or 0, if the address is not bound to a port.
+func localPort(address net.Addr) uint16 {
+	port, err := addressPort(address.String())
+
+	if err != nil {
+		return 0
+	}
+
+	return port
+}
@@ -0,0 +1,248 @@
+//go:build !windows
+
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/revx-official/revx/pkg/systemd"
+)
+
+// Constant declarations.
+const (
+	// The environment variable carrying the amount of sockets passed to a new process.
+	// The sockets are passed as file descriptors, starting at 3.
+	envUpgradeFds string = "REVX_UPGRADE_FDS"
+
+	// The environment variable carrying the file descriptor used by a new process to report readiness.
+	envUpgradeReadyFd string = "REVX_UPGRADE_READY_FD"
+
+	// The first file descriptor passed to a child process.
+	firstExtraFd int = 3
+)
+
+// Description:
+//
+//	Registers the given channel to receive the upgrade signal SIGUSR2.
+//
+// Parameters:
+//
+//	signals The channel receiving the signal.
+func NotifyUpgrade(signals chan<- os.Signal) {
+	signal.Notify(signals, syscall.SIGUSR2)
+}
+
+// Description:
+//
+//	Starts a new revx process, using the current executable and command line arguments,
+//	and passes all sockets created by this process to it.
+//	Blocks until the new process reported readiness.
+//	If the new process fails or does not report readiness in time, it is killed.
+//
+// Parameters:
+//
+//	timeout The maximum time to wait for the new process to report readiness.
+//
+// Returns:
+//
+//	An error, if the upgrade failed. The current process keeps serving in that case.
+func Upgrade(timeout time.Duration) error {
+	executable, err := os.Executable()
+
+	if err != nil {
+		return fmt.Errorf("socket: unable to locate executable: %s", err)
+	}
+
+	files := []*os.File{}
+
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	mutex.Lock()
+
+	for _, socket := range sockets {
+		file, err := socket.File()
+
+		if err != nil {
+			mutex.Unlock()
+			return fmt.Errorf("socket: unable to pass socket: %s", err)
+		}
+
+		files = append(files, file)
+	}
+
+	mutex.Unlock()
+
+	ready, readyWriter, err := os.Pipe()
+
+	if err != nil {
+		return fmt.Errorf("socket: unable to create readiness pipe: %s", err)
+	}
+
+	defer ready.Close()
+
+	command := exec.Command(executable, os.Args[1:]...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.ExtraFiles = append(files, readyWriter)
+	command.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envUpgradeFds, len(files)),
+		fmt.Sprintf("%s=%d", envUpgradeReadyFd, firstExtraFd+len(files)),
+	)
+
+	log.Infof("socket: starting new process: %s", executable)
+	err = command.Start()
+	readyWriter.Close()
+
+	if err != nil {
+		return fmt.Errorf("socket: unable to start new process: %s", err)
+	}
+
+	go command.Wait()
+
+	result := make(chan error, 1)
+
+	go func() {
+		buffer := make([]byte, 1)
+		_, err := ready.Read(buffer)
+		result <- err
+	}()
+
+	select {
+	case err = <-result:
+	case <-time.After(timeout):
+		err = fmt.Errorf("timeout")
+	}
+
+	if err != nil {
+		command.Process.Kill()
+		return fmt.Errorf("socket: new process did not report readiness: %s", err)
+	}
+
+	log.Infof("socket: new process ready: pid: %d", command.Process.Pid)
+	return nil
+}
+
+// Description:
+//
+//	Reports readiness to the parent process, if this process was started by an upgrade.
+func NotifyUpgradeReady() {
+	value, exists := os.LookupEnv(envUpgradeReadyFd)
+
+	if !exists {
+		return
+	}
+
+	os.Unsetenv(envUpgradeReadyFd)
+	fd, err := strconv.Atoi(value)
+
+	if err != nil {
+		log.Warnf("socket: invalid readiness file descriptor: %s", value)
+		return
+	}
+
+	file := os.NewFile(uintptr(fd), "ready")
+	defer file.Close()
+
+	_, err = file.Write([]byte{1})
+
+	if err != nil {
+		log.Warnf("socket: unable to report readiness: %s", err)
+	}
+}
+
+// Description:
+//
+//	Collects the sockets passed by the parent process during an upgrade,
+//	or by systemd socket activation.
+//
+// Returns:
+//
+//	The inherited listeners and packet connections, or an error.
+func inheritedSockets() ([]net.Listener, []net.PacketConn, error) {
+	value, exists := os.LookupEnv(envUpgradeFds)
+
+	if !exists {
+		count, _ := systemd.ListenFds()
+
+		if count == 0 {
+			return nil, nil, nil
+		}
+
+		return filesToSockets(systemd.ListenFdsStart, count, "systemd")
+	}
+
+	os.Unsetenv(envUpgradeFds)
+	count, err := strconv.Atoi(value)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("socket: invalid amount of inherited sockets: %s", value)
+	}
+
+	return filesToSockets(firstExtraFd, count, "upgrade")
+}
+
+// Description:
+//
+//	Creates listeners and packet connections from consecutive file descriptors.
+//	Stream sockets become listeners, datagram sockets become packet connections.
+//
+// Parameters:
+//
+//	first 	The first file descriptor.
+//	count 	The amount of file descriptors.
+//	name 	The name used for the file descriptors.
+//
+// Returns:
+//
+//	The listeners and packet connections, or an error.
+func filesToSockets(first int, count int, name string) ([]net.Listener, []net.PacketConn, error) {
+	listeners := []net.Listener{}
+	packets := []net.PacketConn{}
+
+	for fd := first; fd < first+count; fd++ {
+		syscall.CloseOnExec(fd)
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("%s-%d", name, fd))
+
+		socketType, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+
+		if err != nil {
+			file.Close()
+			return listeners, packets, fmt.Errorf("socket: unable to use file descriptor %d: %s", fd, err)
+		}
+
+		if socketType == syscall.SOCK_DGRAM {
+			conn, err := net.FilePacketConn(file)
+			file.Close()
+
+			if err != nil {
+				return listeners, packets, fmt.Errorf("socket: unable to use file descriptor %d: %s", fd, err)
+			}
+
+			packets = append(packets, conn)
+			continue
+		}
+
+		listener, err := net.FileListener(file)
+		file.Close()
+
+		if err != nil {
+			return listeners, packets, fmt.Errorf("socket: unable to use file descriptor %d: %s", fd, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, packets, nil
+}
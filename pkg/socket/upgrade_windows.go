@@ -0,0 +1,52 @@
+//go:build windows
+
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Description:
+//
+//	Binary upgrades are not supported on windows.
+//
+// Parameters:
+//
+//	signals The channel receiving the signal.
+func NotifyUpgrade(signals chan<- os.Signal) {
+}
+
+// Description:
+//
+//	Binary upgrades are not supported on windows.
+//
+// Parameters:
+//
+//	timeout The maximum time to wait for the new process to report readiness.
+//
+// Returns:
+//
+//	Always an error.
+func Upgrade(timeout time.Duration) error {
+	return fmt.Errorf("socket: binary upgrades are not supported on windows")
+}
+
+// Description:
+//
+//	Binary upgrades are not supported on windows.
+func NotifyUpgradeReady() {
+}
+
+// Description:
+//
+//	Binary upgrades are not supported on windows.
+//
+// Returns:
+//
+//	No sockets.
+func inheritedSockets() ([]net.Listener, []net.PacketConn, error) {
+	return nil, nil, nil
+}
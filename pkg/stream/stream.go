@@ -0,0 +1,411 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/health"
+	"github.com/revx-official/revx/pkg/ipfilter"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/socket"
+)
+
+// The stream subsystem logger.
+var log = logging.NewLogger("stream")
+
+// Constant declarations.
+const (
+	// The maximum time to connect to an upstream, if none is configured.
+	defaultConnectTimeout = 5 * time.Second
+
+	// The time after which an idle udp session is closed, if none is configured.
+	defaultUdpIdleTimeout = 30 * time.Second
+)
+
+// Description:
+//
+//	Represents a layer 4 stream server.
+//	Upstreams, health checks, load balancing and statistics are shared with http servers,
+//	hence every stream server is backed by a reverse proxy server.
+type StreamServer struct {
+	Proxy          *proxy.ReverseProxyServerInfo // The reverse proxy server holding the upstreams.
+	connectTimeout time.Duration
+	idleTimeout    time.Duration
+	filter         *ipfilter.Filter
+	listener       net.Listener
+	packets        net.PacketConn
+	sessions       map[string]*udpSession
+	conns          map[net.Conn]struct{}
+	active         sync.WaitGroup
+	closed         atomic.Bool
+	mutex          sync.Mutex
+}
+
+// All running stream servers.
+var Servers []*StreamServer
+
+// The mutex used to control access to the stream servers.
+var mutex = sync.Mutex{}
+
+// Description:
+//
+//	Creates a new stream server based on the given configuration.
+//	Registers its reverse proxy server in the global proxy manager.
+//
+// Parameters:
+//
+//	conf The stream server configuration.
+//
+// Returns:
+//
+//	The created stream server, or an error, if the configuration is invalid.
+func NewStreamServer(conf config.ConfigStreamServer) (*StreamServer, error) {
+	if conf.Protocol != proxy.ProtocolTcp && conf.Protocol != proxy.ProtocolUdp {
+		return nil, fmt.Errorf("stream: unsupported protocol: %s", conf.Protocol)
+	}
+
+	strategy, err := proxy.BalancingStrategy(conf.LoadBalancing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prox := &proxy.ReverseProxyServerInfo{}
+
+	prox.Name = conf.Name
+	prox.Listen = conf.Listen
+	prox.Protocol = conf.Protocol
+	prox.BalancerInfo.Strategy = strategy
+
+	prox.HealthCheckInfo.Interval = conf.HealthCheck.Interval
+	prox.HealthCheckInfo.Fails = conf.HealthCheck.Fails
+	prox.HealthCheckInfo.Send = conf.HealthCheck.Send
+	prox.HealthCheckInfo.Expect = conf.HealthCheck.Expect
+
+	if conf.HealthCheck.Interval > 0 && conf.Protocol == proxy.ProtocolUdp && conf.HealthCheck.Send == "" {
+		return nil, fmt.Errorf("stream: udp health checks require a send datagram")
+	}
+
+	for _, address := range conf.Upstreams {
+		_, _, err := net.SplitHostPort(address)
+
+		if err != nil {
+			return nil, fmt.Errorf("stream: invalid upstream address: %s: %s", address, err)
+		}
+
+		upstream := &proxy.ReverseProxyServerUpstreamInfo{
+			TargetUrl:   &url.URL{Scheme: conf.Protocol, Host: address},
+			HealthStats: proxy.ReverseProxyServerUpstreamHealthStats{Healthy: true},
+			Stats:       proxy.NewReverseProxyServerUpstreamStats(),
+		}
+
+		prox.Upstreams = append(prox.Upstreams, upstream)
+	}
+
+	filter, err := ipfilter.NewGlobalFilter(config.Global.IpFilter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	server := &StreamServer{
+		Proxy:          prox,
+		filter:         filter,
+		connectTimeout: time.Duration(conf.ConnectTimeout) * time.Millisecond,
+		idleTimeout:    time.Duration(conf.IdleTimeout) * time.Millisecond,
+		sessions:       make(map[string]*udpSession),
+		conns:          make(map[net.Conn]struct{}),
+	}
+
+	if server.connectTimeout == 0 {
+		server.connectTimeout = defaultConnectTimeout
+	}
+
+	if server.idleTimeout == 0 && conf.Protocol == proxy.ProtocolUdp {
+		server.idleTimeout = defaultUdpIdleTimeout
+	}
+
+	proxy.RegisterProxy(prox)
+	return server, nil
+}
+
+// Description:
+//
+//	Creates and starts all stream servers of the global configuration.
+//	Health check routines are started for every stream server with a health check interval.
+//
+// Returns:
+//
+//	An error, if a stream server cannot be created or cannot listen.
+func InitStreams() error {
+	for _, conf := range config.Global.Streams {
+		server, err := NewStreamServer(conf)
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", conf.Name, err)
+		}
+
+		err = server.listen()
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", conf.Name, err)
+		}
+
+		if conf.HealthCheck.Interval > 0 {
+			healthCheck := health.NewHealthCheckRoutine(server.Proxy)
+			health.RunHealthCheckRoutine(healthCheck)
+		}
+
+		mutex.Lock()
+		Servers = append(Servers, server)
+		mutex.Unlock()
+
+		log.Infof("stream: serving %s on %s: %s", conf.Protocol, conf.Listen, conf.Name)
+		go server.serve()
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Gracefully shuts down all stream servers at the same time.
+//	Stops accepting connections and waits for open tcp connections to complete.
+//	Udp sessions are closed right away.
+//
+// Parameters:
+//
+//	ctx The context limiting the time to drain open connections.
+//
+// Returns:
+//
+//	An error, if the context expired before all connections completed.
+func Shutdown(ctx context.Context) error {
+	mutex.Lock()
+	servers := Servers
+	mutex.Unlock()
+
+	errs := make(chan error, len(servers))
+
+	for _, server := range servers {
+		go func(server *StreamServer) {
+			errs <- server.Shutdown(ctx)
+		}(server)
+	}
+
+	var result error
+
+	for range servers {
+		err := <-errs
+
+		if err != nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// Description:
+//
+//	Gracefully shuts down the stream server.
+//	If the context expires first, all remaining connections are closed.
+//
+// Parameters:
+//
+//	ctx The context limiting the time to drain open connections.
+//
+// Returns:
+//
+//	An error, if the context expired before all connections completed.
+func (server *StreamServer) Shutdown(ctx context.Context) error {
+	// Set under the mutex, so no connection or session is added to the wait group once draining started.
+	server.mutex.Lock()
+	server.closed.Store(true)
+	server.mutex.Unlock()
+
+	if server.listener != nil {
+		server.listener.Close()
+	}
+
+	if server.packets != nil {
+		server.packets.Close()
+		server.closeSessions()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		server.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	server.mutex.Lock()
+
+	for conn := range server.conns {
+		conn.Close()
+	}
+
+	server.mutex.Unlock()
+	return ctx.Err()
+}
+
+// Description:
+//
+//	Creates the listener or packet connection of the stream server.
+//
+// Returns:
+//
+//	An error, if the address cannot be listened on.
+func (server *StreamServer) listen() error {
+	var err error
+
+	if server.Proxy.Protocol == proxy.ProtocolUdp {
+		server.packets, err = socket.ListenUdp(server.Proxy.Listen)
+	} else {
+		server.listener, err = socket.ListenTcp(server.Proxy.Listen)
+	}
+
+	return err
+}
+
+// Description:
+//
+//	Serves the stream server until it is shut down.
+func (server *StreamServer) serve() {
+	if server.Proxy.Protocol == proxy.ProtocolUdp {
+		server.serveUdp()
+		return
+	}
+
+	server.serveTcp()
+}
+
+// Description:
+//
+//	Checks whether a client passes the global ip filter rules.
+//
+// Parameters:
+//
+//	client The client address.
+//
+// Returns:
+//
+//	True, if the client is allowed.
+func (server *StreamServer) allows(client net.Addr) bool {
+	if server.filter.Empty() {
+		return true
+	}
+
+	ip, allowed := server.filter.Check(client.String(), "")
+
+	if !allowed {
+		log.Debugf("stream: rejected client: %s: %s", server.Proxy.Name, ip)
+	}
+
+	return allowed
+}
+
+// Description:
+//
+//	Registers an accepted connection, unless the stream server is shut down.
+//	The connection is added to the wait group and tracked, so it is drained and can be closed if draining times out.
+//
+// Parameters:
+//
+//	conn The accepted connection.
+//
+// Returns:
+//
+//	False, if the stream server is shut down.
+func (server *StreamServer) accept(conn net.Conn) bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.closed.Load() {
+		return false
+	}
+
+	server.active.Add(1)
+	server.conns[conn] = struct{}{}
+
+	return true
+}
+
+// Description:
+//
+//	Tracks an open connection, so it can be closed if draining times out.
+//
+// Parameters:
+//
+//	conn 	The connection.
+//	open 	Whether the connection has been opened or closed.
+func (server *StreamServer) track(conn net.Conn, open bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if open {
+		server.conns[conn] = struct{}{}
+	} else {
+		delete(server.conns, conn)
+	}
+}
+
+// Description:
+//
+//	Tracks the last activity of a connection in both directions,
+//	in order to close the connection once it has been idle for too long.
+type activity struct {
+	last atomic.Int64
+}
+
+// Description:
+//
+//	Marks the connection as active.
+func (activity *activity) touch() {
+	activity.last.Store(time.Now().UnixNano())
+}
+
+// Description:
+//
+//	Gets the point in time at which the connection becomes idle.
+//
+// Parameters:
+//
+//	timeout The idle timeout.
+//
+// Returns:
+//
+//	The point in time at which the connection becomes idle.
+func (activity *activity) deadline(timeout time.Duration) time.Time {
+	return time.Unix(0, activity.last.Load()).Add(timeout)
+}
+
+// Description:
+//
+//	Checks whether an error is a timeout.
+//
+// Parameters:
+//
+//	err The error.
+//
+// Returns:
+//
+//	True, if the error is a timeout.
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/revx-official/revx/pkg/proxy"
+)
+
+// Description:
+//
+//	Accepts tcp connections until the stream server is shut down.
+func (server *StreamServer) serveTcp() {
+	for {
+		conn, err := server.listener.Accept()
+
+		if err != nil {
+			if server.closed.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			log.Warnf("stream: unable to accept connection: %s: %s", server.Proxy.Name, err)
+			time.Sleep(10 * time.Millisecond)
+
+			continue
+		}
+
+		if !server.allows(conn.RemoteAddr()) {
+			conn.Close()
+			continue
+		}
+
+		if !server.accept(conn) {
+			conn.Close()
+			return
+		}
+
+		go server.handleTcp(conn)
+	}
+}
+
+// Description:
+//
+//	Passes a tcp connection to an upstream, selected by the load balancer.
+//	Blocks until both directions of the connection are closed.
+//	The connection has to be registered by accept beforehand.
+//
+// Parameters:
+//
+//	client The client connection.
+func (server *StreamServer) handleTcp(client net.Conn) {
+	defer server.active.Done()
+	defer client.Close()
+	defer server.track(client, false)
+
+	instance := proxy.SelectUpstream(server.Proxy, false)
+
+	if instance == nil {
+		log.Warnf("stream: no upstream available: %s", server.Proxy.Name)
+		return
+	}
+
+	defer proxy.ReleaseUpstream(instance, false)
+
+	stats := instance.Stats
+	stats.Begin()
+	defer stats.End()
+
+	start := time.Now()
+	upstream, err := net.DialTimeout("tcp", instance.TargetUrl.Host, server.connectTimeout)
+	stats.RecordConnection(err == nil, time.Since(start))
+
+	if err != nil {
+		log.Warnf("stream: unable to connect to upstream: %s: %s", instance.TargetUrl.Host, err)
+		return
+	}
+
+	defer upstream.Close()
+
+	server.track(upstream, true)
+	defer server.track(upstream, false)
+
+	log.Tracef("stream: pass %s to %s", client.RemoteAddr(), instance.TargetUrl.Host)
+
+	active := &activity{}
+	active.touch()
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		server.pipe(upstream, client, active, stats.AddBytesSent)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		server.pipe(client, upstream, active, stats.AddBytesReceived)
+		done <- struct{}{}
+	}()
+
+	<-done
+	<-done
+}
+
+// Description:
+//
+//	Copies one direction of a tcp connection.
+//	Once the source is closed, the write side of the destination is closed as well,
+//	so half-closed connections keep working. On errors, both connections are closed.
+//
+// Parameters:
+//
+//	destination The connection to write to.
+//	source 		The connection to read from.
+//	active 		The activity of the connection, shared by both directions.
+//	count 		Reports the amount of copied bytes.
+func (server *StreamServer) pipe(destination net.Conn, source net.Conn, active *activity, count func(int)) {
+	buffer := make([]byte, 32*1024)
+
+	for {
+		if server.idleTimeout > 0 {
+			source.SetReadDeadline(active.deadline(server.idleTimeout))
+		}
+
+		read, err := source.Read(buffer)
+
+		if read > 0 {
+			active.touch()
+			count(read)
+
+			_, writeErr := destination.Write(buffer[:read])
+
+			if writeErr != nil {
+				err = writeErr
+			}
+		}
+
+		if err == nil {
+			continue
+		}
+
+		// The other direction may still be active.
+		if isTimeout(err) && time.Now().Before(active.deadline(server.idleTimeout)) {
+			continue
+		}
+
+		if err == io.EOF {
+			if closer, ok := destination.(interface{ CloseWrite() error }); ok {
+				closer.CloseWrite()
+				return
+			}
+		}
+
+		destination.Close()
+		source.Close()
+
+		return
+	}
+}
@@ -0,0 +1,189 @@
+package stream
+
+import (
+	"net"
+	"time"
+
+	"github.com/revx-official/revx/pkg/proxy"
+)
+
+// The maximum size of a udp datagram.
+const udpMaxDatagramSize = 64 * 1024
+
+// Description:
+//
+//	A udp session, i.e. all datagrams of a single client address.
+//	All datagrams of a session are passed to the same upstream.
+type udpSession struct {
+	client   net.Addr
+	instance *proxy.ReverseProxyServerUpstreamInfo
+	upstream net.Conn
+	active   activity
+}
+
+// Description:
+//
+//	Receives udp datagrams until the stream server is shut down.
+func (server *StreamServer) serveUdp() {
+	buffer := make([]byte, udpMaxDatagramSize)
+
+	for {
+		read, client, err := server.packets.ReadFrom(buffer)
+
+		if err != nil {
+			if server.closed.Load() {
+				return
+			}
+
+			log.Warnf("stream: unable to receive datagram: %s: %s", server.Proxy.Name, err)
+			time.Sleep(10 * time.Millisecond)
+
+			continue
+		}
+
+		session := server.session(client)
+
+		if session == nil {
+			continue
+		}
+
+		session.active.touch()
+		_, err = session.upstream.Write(buffer[:read])
+
+		if err != nil {
+			log.Debugf("stream: unable to pass datagram: %s: %s", session.instance.TargetUrl.Host, err)
+			continue
+		}
+
+		session.instance.Stats.AddBytesSent(read)
+	}
+}
+
+// Description:
+//
+//	Gets the session of a client address.
+//	Creates a new session, if the client has no session yet.
+//
+// Parameters:
+//
+//	client The client address.
+//
+// Returns:
+//
+//	The session, or nil, if the client is rejected, no upstream is available or the stream server is shut down.
+func (server *StreamServer) session(client net.Addr) *udpSession {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.closed.Load() {
+		return nil
+	}
+
+	session, exists := server.sessions[client.String()]
+
+	if exists {
+		return session
+	}
+
+	if !server.allows(client) {
+		return nil
+	}
+
+	instance := proxy.SelectUpstream(server.Proxy, false)
+
+	if instance == nil {
+		log.Warnf("stream: no upstream available: %s", server.Proxy.Name)
+		return nil
+	}
+
+	start := time.Now()
+	upstream, err := net.DialTimeout("udp", instance.TargetUrl.Host, server.connectTimeout)
+	instance.Stats.RecordConnection(err == nil, time.Since(start))
+
+	if err != nil {
+		log.Warnf("stream: unable to connect to upstream: %s: %s", instance.TargetUrl.Host, err)
+		proxy.ReleaseUpstream(instance, false)
+
+		return nil
+	}
+
+	log.Tracef("stream: new session %s to %s", client, instance.TargetUrl.Host)
+
+	session = &udpSession{client: client, instance: instance, upstream: upstream}
+	session.active.touch()
+	server.sessions[client.String()] = session
+
+	instance.Stats.Begin()
+	server.active.Add(1)
+
+	go server.replies(session)
+	return session
+}
+
+// Description:
+//
+//	Passes the datagrams of the upstream back to the client, until the session is idle or closed.
+//
+// Parameters:
+//
+//	session The session.
+func (server *StreamServer) replies(session *udpSession) {
+	defer server.active.Done()
+	defer proxy.ReleaseUpstream(session.instance, false)
+	defer session.instance.Stats.End()
+	defer server.removeSession(session)
+
+	buffer := make([]byte, udpMaxDatagramSize)
+
+	for {
+		session.upstream.SetReadDeadline(session.active.deadline(server.idleTimeout))
+		read, err := session.upstream.Read(buffer)
+
+		if err != nil {
+			if isTimeout(err) && time.Now().Before(session.active.deadline(server.idleTimeout)) {
+				continue
+			}
+
+			return
+		}
+
+		session.active.touch()
+		session.instance.Stats.AddBytesReceived(read)
+
+		_, err = server.packets.WriteTo(buffer[:read], session.client)
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Description:
+//
+//	Removes a session and closes its upstream connection.
+//
+// Parameters:
+//
+//	session The session.
+func (server *StreamServer) removeSession(session *udpSession) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.sessions[session.client.String()] == session {
+		delete(server.sessions, session.client.String())
+	}
+
+	session.upstream.Close()
+}
+
+// Description:
+//
+//	Closes all udp sessions.
+func (server *StreamServer) closeSessions() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for _, session := range server.sessions {
+		session.upstream.Close()
+	}
+}
@@ -0,0 +1,186 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/revx-official/revx/pkg/logging"
+)
+
+// The systemd subsystem logger.
+var log = logging.NewLogger("systemd")
+
+// Constant declarations.
+const (
+	// The first file descriptor passed by systemd socket activation.
+	ListenFdsStart int = 3
+
+	// Tells the service manager that startup is finished.
+	StateReady string = "READY=1"
+
+	// Tells the service manager that the service is reloading.
+	StateReloading string = "RELOADING=1"
+
+	// Tells the service manager that the service is shutting down.
+	StateStopping string = "STOPPING=1"
+
+	// Keeps the service manager watchdog from restarting the service.
+	StateWatchdog string = "WATCHDOG=1"
+)
+
+// Description:
+//
+//	Gets the amount of listening sockets passed by systemd socket activation.
+//	The environment variables are removed, so they are not inherited by child processes.
+//
+// Returns:
+//
+//	The amount of passed file descriptors, starting at ListenFdsStart, and their names.
+func ListenFds() (int, []string) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+
+	if err != nil || pid != os.Getpid() {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+
+	if err != nil || count <= 0 {
+		return 0, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	return count, names
+}
+
+// Description:
+//
+//	Sends a state notification to the service manager.
+//	Does nothing, if revx has not been started by a service manager supporting notifications.
+//
+// Parameters:
+//
+//	states The states to send, e.g. READY=1.
+//
+// Returns:
+//
+//	An error, if the notification cannot be sent.
+func Notify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+
+	if socket == "" {
+		return nil
+	}
+
+	address := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	connection, err := net.DialUnix("unixgram", nil, address)
+
+	if err != nil {
+		return fmt.Errorf("systemd: unable to connect to notify socket: %s", err)
+	}
+
+	defer connection.Close()
+
+	_, err = connection.Write([]byte(strings.Join(states, "\n")))
+
+	if err != nil {
+		return fmt.Errorf("systemd: unable to notify: %s", err)
+	}
+
+	log.Tracef("systemd: notified: %s", strings.Join(states, ", "))
+	return nil
+}
+
+// Description:
+//
+//	Notifies the service manager that revx is ready.
+//	Includes the process id, since it changes during binary upgrades.
+func NotifyReady() {
+	err := Notify(StateReady, fmt.Sprintf("MAINPID=%d", os.Getpid()))
+
+	if err != nil {
+		log.Warnf("%s", err)
+	}
+}
+
+// Description:
+//
+//	Notifies the service manager that revx is reloading.
+func NotifyReloading() {
+	err := Notify(StateReloading)
+
+	if err != nil {
+		log.Warnf("%s", err)
+	}
+}
+
+// Description:
+//
+//	Notifies the service manager that revx is shutting down.
+func NotifyStopping() {
+	err := Notify(StateStopping)
+
+	if err != nil {
+		log.Warnf("%s", err)
+	}
+}
+
+// Description:
+//
+//	Gets the watchdog interval requested by the service manager.
+//
+// Returns:
+//
+//	The watchdog interval, or 0, if the watchdog is disabled.
+func WatchdogInterval() time.Duration {
+	pid := os.Getenv("WATCHDOG_PID")
+
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Description:
+//
+//	Pings the service manager watchdog at half the requested interval, until the process exits.
+//	Does nothing, if the watchdog is disabled.
+func RunWatchdog() {
+	interval := WatchdogInterval()
+
+	if interval == 0 {
+		return
+	}
+
+	// A process started by a binary upgrade becomes the main process and takes over the watchdog.
+	os.Unsetenv("WATCHDOG_PID")
+	log.Infof("systemd: pinging watchdog every %s", interval/2)
+
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			err := Notify(StateWatchdog)
+
+			if err != nil {
+				log.Warnf("%s", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,412 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/revx"
+)
+
+// Constant declarations.
+const (
+	// The OpenTelemetry status code of failed spans.
+	otlpStatusCodeError int = 2
+
+	// The timeout of a single export request.
+	exportTimeout = 10 * time.Second
+)
+
+// Description:
+//
+//	Exports finished spans in batches to an OTLP/HTTP collector.
+//	Spans are encoded using the OTLP json encoding.
+//	If the export queue is full, spans are dropped.
+type Exporter struct {
+	conf     config.ConfigTracing
+	client   *http.Client
+	queue    chan *Span
+	flush    chan chan struct{}
+	done     chan struct{}
+	interval time.Duration
+}
+
+// Description:
+//
+//	Creates a new exporter and starts its export routine.
+//
+// Parameters:
+//
+//	conf The tracing configuration.
+//
+// Returns:
+//
+//	The created exporter, or an error, if the collector endpoint is invalid.
+func NewExporter(conf config.ConfigTracing) (*Exporter, error) {
+	endpoint, err := url.Parse(conf.Endpoint)
+
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("tracing: invalid collector endpoint: %s", conf.Endpoint)
+	}
+
+	batchSize := int(conf.BatchSize)
+
+	if batchSize == 0 {
+		batchSize = 512
+	}
+
+	interval := time.Duration(conf.FlushInterval) * time.Millisecond
+
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	exporter := Exporter{
+		conf:     conf,
+		client:   &http.Client{Timeout: exportTimeout},
+		queue:    make(chan *Span, batchSize*4),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+		interval: interval,
+	}
+
+	go exporter.run(batchSize)
+	return &exporter, nil
+}
+
+// Description:
+//
+//	Queues a finished span for export.
+//	Never blocks, the span is dropped if the queue is full.
+//
+// Parameters:
+//
+//	span The span to export.
+func (exporter *Exporter) Export(span *Span) {
+	select {
+	case exporter.queue <- span:
+	default:
+		log.Warnf("tracing: export queue full, dropping span: %s", span.Name)
+	}
+}
+
+// Description:
+//
+//	Exports all queued spans and stops the export routine.
+//
+// Parameters:
+//
+//	ctx The context limiting the time to flush.
+//
+// Returns:
+//
+//	An error, if flushing did not complete in time.
+func (exporter *Exporter) Shutdown(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	select {
+	case exporter.flush <- flushed:
+	case <-exporter.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Description:
+//
+//	The export routine.
+//	Sends a batch whenever it is full or the flush interval elapsed.
+//
+// Parameters:
+//
+//	batchSize The maximum amount of spans per export request.
+func (exporter *Exporter) run(batchSize int) {
+	ticker := time.NewTicker(exporter.interval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+
+	for {
+		select {
+		case span := <-exporter.queue:
+			batch = append(batch, span)
+
+			if len(batch) >= batchSize {
+				exporter.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			exporter.send(batch)
+			batch = batch[:0]
+		case flushed := <-exporter.flush:
+			for len(exporter.queue) > 0 {
+				batch = append(batch, <-exporter.queue)
+			}
+
+			exporter.send(batch)
+			close(exporter.done)
+			close(flushed)
+
+			return
+		}
+	}
+}
+
+// Description:
+//
+//	Sends a batch of spans to the collector.
+//
+// Parameters:
+//
+//	batch The spans to send.
+func (exporter *Exporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(exporter.encode(batch))
+
+	if err != nil {
+		log.Errorf("tracing: unable to encode spans: %s", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, exporter.conf.Endpoint, bytes.NewReader(body))
+
+	if err != nil {
+		log.Errorf("tracing: unable to create export request: %s", err)
+		return
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	for key, value := range exporter.conf.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := exporter.client.Do(request)
+
+	if err != nil {
+		log.Warnf("tracing: unable to export %d spans: %s", len(batch), err)
+		return
+	}
+
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		log.Warnf("tracing: collector rejected %d spans: status: %d", len(batch), response.StatusCode)
+		return
+	}
+
+	log.Tracef("tracing: exported %d spans", len(batch))
+}
+
+// Description:
+//
+//	Encodes a batch of spans as OTLP json export request.
+//
+// Parameters:
+//
+//	batch The spans to encode.
+//
+// Returns:
+//
+//	The OTLP export request.
+func (exporter *Exporter) encode(batch []*Span) otlpExportRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+
+	for _, span := range batch {
+		spans = append(spans, encodeSpan(span))
+	}
+
+	resource := otlpResource{
+		Attributes: encodeAttributes(map[string]interface{}{
+			"service.name":    exporter.conf.ServiceName,
+			"service.version": revx.RevxVersion,
+		}),
+	}
+
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "revx", Version: revx.RevxVersion},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+// Description:
+//
+//	Encodes a single span.
+//
+// Parameters:
+//
+//	span The span to encode.
+//
+// Returns:
+//
+//	The OTLP span.
+func encodeSpan(span *Span) otlpSpan {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	result := otlpSpan{
+		TraceId:           span.Context.TraceId.String(),
+		SpanId:            span.Context.SpanId.String(),
+		TraceState:        span.Context.TraceState,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        encodeAttributes(span.Attributes),
+	}
+
+	if span.Parent.IsValid() {
+		result.ParentSpanId = span.Parent.String()
+	}
+
+	if span.Error {
+		result.Status = &otlpStatus{Code: otlpStatusCodeError, Message: span.StatusMessage}
+	}
+
+	return result
+}
+
+// Description:
+//
+//	Encodes span attributes.
+//
+// Parameters:
+//
+//	attributes The attributes to encode.
+//
+// Returns:
+//
+//	The OTLP attributes.
+func encodeAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(attributes))
+
+	for key, value := range attributes {
+		encoded := otlpAnyValue{}
+
+		switch typed := value.(type) {
+		case string:
+			encoded.StringValue = &typed
+		case bool:
+			encoded.BoolValue = &typed
+		case int:
+			integer := strconv.Itoa(typed)
+			encoded.IntValue = &integer
+		case int64:
+			integer := strconv.FormatInt(typed, 10)
+			encoded.IntValue = &integer
+		case float64:
+			encoded.DoubleValue = &typed
+		default:
+			text := fmt.Sprint(typed)
+			encoded.StringValue = &text
+		}
+
+		result = append(result, otlpKeyValue{Key: key, Value: encoded})
+	}
+
+	return result
+}
+
+// Description:
+//
+//	The OTLP json export request.
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// Description:
+//
+//	The OTLP spans of a single resource.
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+// Description:
+//
+//	The OTLP resource.
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+// Description:
+//
+//	The OTLP spans of a single instrumentation scope.
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+// Description:
+//
+//	The OTLP instrumentation scope.
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Description:
+//
+//	The OTLP span.
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+// Description:
+//
+//	The OTLP span status.
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Description:
+//
+//	The OTLP attribute.
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// Description:
+//
+//	The OTLP attribute value.
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
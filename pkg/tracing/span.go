@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Description:
+//
+//	The span kind, as defined by OpenTelemetry.
+type SpanKind = int
+
+// Constant declarations.
+const (
+	// A span describing the server side handling of a request.
+	SpanKindServer SpanKind = 2
+
+	// A span describing a request to a remote service.
+	SpanKindClient SpanKind = 3
+)
+
+// Description:
+//
+//	The context key type used to store spans.
+type spanContextKey struct{}
+
+// Description:
+//
+//	Represents a single span of a trace.
+//	Spans of unsampled traces are propagated, but never exported.
+//	All methods are safe to call on a nil span.
+type Span struct {
+	mutex         sync.Mutex
+	tracer        *Tracer
+	Context       SpanContext            // The span context.
+	Parent        SpanId                 // The parent span id, if any.
+	Name          string                 // The span name.
+	Kind          SpanKind               // The span kind.
+	Start         time.Time              // The start time.
+	End           time.Time              // The end time.
+	Attributes    map[string]interface{} // The span attributes.
+	Error         bool                   // Whether the span failed.
+	StatusMessage string                 // The status message of a failed span.
+}
+
+// Description:
+//
+//	Sets an attribute of the span.
+//	Supported value types are strings, booleans, integers and floats.
+//
+// Parameters:
+//
+//	key 	The attribute key.
+//	value 	The attribute value.
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	span.Attributes[key] = value
+}
+
+// Description:
+//
+//	Marks the span as failed.
+//
+// Parameters:
+//
+//	message The status message.
+func (span *Span) SetError(message string) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	span.Error = true
+	span.StatusMessage = message
+}
+
+// Description:
+//
+//	Finishes the span.
+//	Sampled spans are handed to the exporter.
+func (span *Span) Finish() {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	span.End = time.Now()
+	span.mutex.Unlock()
+
+	if span.Context.Sampled && span.tracer.exporter != nil {
+		span.tracer.exporter.Export(span)
+	}
+}
+
+// Description:
+//
+//	Attaches a span to a context.
+//
+// Parameters:
+//
+//	ctx 	The parent context.
+//	span 	The span to attach.
+//
+// Returns:
+//
+//	The context carrying the span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// Description:
+//
+//	Gets the span attached to a context.
+//
+// Parameters:
+//
+//	ctx The context.
+//
+// Returns:
+//
+//	The span, or nil, if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Description:
+//
+//	Starts a child span of the span attached to the given context.
+//
+// Parameters:
+//
+//	ctx 	The context carrying the parent span.
+//	name 	The span name.
+//	kind 	The span kind.
+//
+// Returns:
+//
+//	The started span, or nil, if the context carries no span.
+func StartSpan(ctx context.Context, name string, kind SpanKind) *Span {
+	parent := SpanFromContext(ctx)
+
+	if parent == nil {
+		return nil
+	}
+
+	spanContext := parent.Context
+	spanContext.SpanId = NewSpanId()
+
+	return parent.tracer.newSpan(spanContext, parent.Context.SpanId, name, kind)
+}
@@ -0,0 +1,237 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Constant declarations.
+const (
+	// The W3C trace context traceparent header.
+	HeaderTraceParent string = "traceparent"
+
+	// The W3C trace context tracestate header.
+	HeaderTraceState string = "tracestate"
+
+	// The trace flag indicating that a trace is sampled.
+	flagSampled byte = 0x01
+
+	// The maximum length of a tracestate header which is propagated.
+	maxTraceStateLength int = 512
+)
+
+// Description:
+//
+//	A trace id, consisting of 16 bytes.
+type TraceId [16]byte
+
+// Description:
+//
+//	A span id, consisting of 8 bytes.
+type SpanId [8]byte
+
+// Description:
+//
+//	Represents a W3C trace context, identifying a single span within a trace.
+type SpanContext struct {
+	TraceId    TraceId // The trace id.
+	SpanId     SpanId  // The span id.
+	Sampled    bool    // Whether the trace is sampled.
+	TraceState string  // The vendor specific trace state, propagated as is.
+}
+
+// Description:
+//
+//	Gets the hex representation of the trace id.
+func (id TraceId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Description:
+//
+//	Checks whether the trace id is valid, i.e. not all zeros.
+func (id TraceId) IsValid() bool {
+	return id != TraceId{}
+}
+
+// Description:
+//
+//	Gets the hex representation of the span id.
+func (id SpanId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Description:
+//
+//	Checks whether the span id is valid, i.e. not all zeros.
+func (id SpanId) IsValid() bool {
+	return id != SpanId{}
+}
+
+// Description:
+//
+//	Generates a new random trace id.
+//
+// Returns:
+//
+//	The generated trace id.
+func NewTraceId() TraceId {
+	id := TraceId{}
+	rand.Read(id[:])
+
+	return id
+}
+
+// Description:
+//
+//	Generates a new random span id.
+//
+// Returns:
+//
+//	The generated span id.
+func NewSpanId() SpanId {
+	id := SpanId{}
+	rand.Read(id[:])
+
+	return id
+}
+
+// Description:
+//
+//	Parses a traceparent header value.
+//	Only the version 00 format is supported, higher versions are parsed by their version 00 prefix.
+//
+// Parameters:
+//
+//	value The traceparent header value.
+//
+// Returns:
+//
+//	The parsed span context, or an error, if the value is invalid.
+func ParseTraceParent(value string) (SpanContext, error) {
+	result := SpanContext{}
+	value = strings.TrimSpace(value)
+
+	if len(value) < 55 || (len(value) > 55 && value[55] != '-') {
+		return result, fmt.Errorf("tracing: invalid traceparent: %s", value)
+	}
+
+	parts := strings.Split(value[:55], "-")
+
+	if len(parts) != 4 || parts[0] == "ff" || len(parts[0]) != 2 {
+		return result, fmt.Errorf("tracing: invalid traceparent: %s", value)
+	}
+
+	if parts[0] == "00" && len(value) != 55 {
+		return result, fmt.Errorf("tracing: invalid traceparent: %s", value)
+	}
+
+	_, versionErr := decodeHex(parts[0], 1)
+	traceId, traceErr := decodeHex(parts[1], 16)
+	spanId, spanErr := decodeHex(parts[2], 8)
+	flags, flagsErr := decodeHex(parts[3], 1)
+
+	if versionErr != nil || traceErr != nil || spanErr != nil || flagsErr != nil {
+		return result, fmt.Errorf("tracing: invalid traceparent: %s", value)
+	}
+
+	copy(result.TraceId[:], traceId)
+	copy(result.SpanId[:], spanId)
+	result.Sampled = flags[0]&flagSampled != 0
+
+	if !result.TraceId.IsValid() || !result.SpanId.IsValid() {
+		return result, fmt.Errorf("tracing: invalid traceparent: %s", value)
+	}
+
+	return result, nil
+}
+
+// Description:
+//
+//	Formats the span context as traceparent header value.
+//
+// Returns:
+//
+//	The traceparent header value.
+func (context SpanContext) TraceParent() string {
+	flags := byte(0)
+
+	if context.Sampled {
+		flags |= flagSampled
+	}
+
+	return fmt.Sprintf("00-%s-%s-%02x", context.TraceId, context.SpanId, flags)
+}
+
+// Description:
+//
+//	Extracts the span context of a request from its trace context headers.
+//
+// Parameters:
+//
+//	header The request headers.
+//
+// Returns:
+//
+//	The extracted span context, and whether a valid span context was found.
+func Extract(header http.Header) (SpanContext, bool) {
+	values := header.Values(HeaderTraceParent)
+
+	if len(values) != 1 {
+		return SpanContext{}, false
+	}
+
+	context, err := ParseTraceParent(values[0])
+
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	state := strings.Join(header.Values(HeaderTraceState), ",")
+
+	if len(state) <= maxTraceStateLength {
+		context.TraceState = state
+	}
+
+	return context, true
+}
+
+// Description:
+//
+//	Injects the span context into request headers.
+//	Replaces any existing trace context headers.
+//
+// Parameters:
+//
+//	header The request headers.
+func (context SpanContext) Inject(header http.Header) {
+	header.Set(HeaderTraceParent, context.TraceParent())
+	header.Del(HeaderTraceState)
+
+	if context.TraceState != "" {
+		header.Set(HeaderTraceState, context.TraceState)
+	}
+}
+
+// Description:
+//
+//	Decodes a lower case hex string of an exact length.
+//
+// Parameters:
+//
+//	value 	The hex string.
+//	length 	The expected amount of bytes.
+//
+// Returns:
+//
+//	The decoded bytes, or an error.
+func decodeHex(value string, length int) ([]byte, error) {
+	if len(value) != length*2 || strings.ToLower(value) != value {
+		return nil, fmt.Errorf("tracing: invalid hex value: %s", value)
+	}
+
+	return hex.DecodeString(value)
+}
@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The tracing subsystem logger.
+var log = logging.NewLogger("tracing")
+
+// Description:
+//
+//	Creates spans and hands finished spans to the exporter.
+type Tracer struct {
+	conf     config.ConfigTracing
+	exporter *Exporter
+}
+
+// The global tracer.
+// Is nil, if tracing is disabled.
+var Global *Tracer
+
+// Description:
+//
+//	Initializes the global tracer using the given configuration.
+//
+// Parameters:
+//
+//	conf The tracing configuration.
+//
+// Returns:
+//
+//	An error, if the configuration is invalid.
+func InitTracing(conf config.ConfigTracing) error {
+	if !conf.Enabled {
+		Global = nil
+		return nil
+	}
+
+	if conf.SampleRate < 0 || conf.SampleRate > 1 {
+		return fmt.Errorf("tracing: sample rate must be between 0 and 1: %f", conf.SampleRate)
+	}
+
+	exporter, err := NewExporter(conf)
+
+	if err != nil {
+		return err
+	}
+
+	Global = &Tracer{conf: conf, exporter: exporter}
+	log.Infof("tracing: exporting spans to: %s", conf.Endpoint)
+
+	return nil
+}
+
+// Description:
+//
+//	Flushes all pending spans and stops the global exporter.
+//
+// Parameters:
+//
+//	ctx The context limiting the time to flush.
+//
+// Returns:
+//
+//	An error, if flushing did not complete in time.
+func Shutdown(ctx context.Context) error {
+	if Global == nil {
+		return nil
+	}
+
+	return Global.exporter.Shutdown(ctx)
+}
+
+// Description:
+//
+//	Gets the sample rate of a server.
+//	Falls back to the global sample rate, if the server does not specify one.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The sample rate between 0 and 1.
+func SampleRate(conf config.ConfigReverseProxyServer) float64 {
+	if conf.Tracing.SampleRate != nil {
+		return *conf.Tracing.SampleRate
+	}
+
+	return config.Global.Tracing.SampleRate
+}
+
+// Description:
+//
+//	Middleware creating a server span for every request.
+//	Continues the trace of the client, if the request carries a valid traceparent header.
+//	Otherwise, a new trace is started and sampled using the given sample rate.
+//	If tracing is disabled, the handler is returned as is.
+//
+// Parameters:
+//
+//	conf 		The server configuration.
+//	clientIp 	Determines the client address of a request.
+//
+// Returns:
+//
+//	The middleware.
+func Middleware(conf config.ConfigReverseProxyServer, clientIp func(request *http.Request) string) router.RouterProxyMiddlewareFunc {
+	sampleRate := SampleRate(conf)
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		tracer := Global
+
+		if tracer == nil {
+			return handler
+		}
+
+		return func(request *http.Request, response http.ResponseWriter) {
+			name := request.Method + " " + conf.Context
+			span := tracer.startServerSpan(request, name, sampleRate)
+
+			span.SetAttribute("revx.server", conf.Name)
+			span.SetAttribute("http.route", conf.Context)
+			span.SetAttribute("http.request.method", request.Method)
+			span.SetAttribute("url.path", request.URL.Path)
+			span.SetAttribute("server.address", request.Host)
+			span.SetAttribute("client.address", clientIp(request))
+			span.SetAttribute("user_agent.original", request.UserAgent())
+
+			recorder := router.NewResponseRecorder(response)
+			request = request.WithContext(ContextWithSpan(request.Context(), span))
+
+			handler(request, recorder)
+
+			span.SetAttribute("http.response.status_code", recorder.Status())
+
+			if recorder.Status() >= http.StatusInternalServerError {
+				span.SetError(http.StatusText(recorder.Status()))
+			}
+
+			span.Finish()
+		}
+	}
+}
+
+// Description:
+//
+//	Starts a server span for an incoming request.
+//
+// Parameters:
+//
+//	request 	The incoming request.
+//	name 		The span name.
+//	sampleRate 	The sample rate used for new traces.
+//
+// Returns:
+//
+//	The started span.
+func (tracer *Tracer) startServerSpan(request *http.Request, name string, sampleRate float64) *Span {
+	parent, exists := Extract(request.Header)
+
+	if !exists {
+		spanContext := SpanContext{
+			TraceId: NewTraceId(),
+			SpanId:  NewSpanId(),
+			Sampled: rand.Float64() < sampleRate,
+		}
+
+		return tracer.newSpan(spanContext, SpanId{}, name, SpanKindServer)
+	}
+
+	spanContext := parent
+	spanContext.SpanId = NewSpanId()
+
+	return tracer.newSpan(spanContext, parent.SpanId, name, SpanKindServer)
+}
+
+// Description:
+//
+//	Creates a new span.
+//
+// Parameters:
+//
+//	spanContext The span context.
+//	parent 		The parent span id.
+//	name 		The span name.
+//	kind 		The span kind.
+//
+// Returns:
+//
+//	The created span.
+func (tracer *Tracer) newSpan(spanContext SpanContext, parent SpanId, name string, kind SpanKind) *Span {
+	return &Span{
+		tracer:     tracer,
+		Context:    spanContext,
+		Parent:     parent,
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+}
@@ -0,0 +1,98 @@
+package waf
+
+import (
+	"strings"
+)
+
+// Description:
+//
+//	A decoded query or form parameter.
+type parameter struct {
+	name  string
+	value string
+}
+
+// Description:
+//
+//	Parses and decodes the parameters of a query string or url encoded form body.
+//	Unlike url.ParseQuery, pairs with invalid escapes or semicolons are kept, so they are inspected as well.
+//
+// Parameters:
+//
+//	text The query string or form body.
+//
+// Returns:
+//
+//	The decoded parameters, in order.
+func parseParameters(text string) []parameter {
+	parameters := []parameter{}
+
+	for _, pair := range strings.Split(text, "&") {
+		if pair == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(pair, "=")
+		parameters = append(parameters, parameter{name: unescape(name), value: unescape(value)})
+	}
+
+	return parameters
+}
+
+// Description:
+//
+//	Decodes a query component like url.QueryUnescape, but keeps invalid escapes as they are,
+//	instead of failing for the whole component.
+//
+// Parameters:
+//
+//	text The encoded text.
+//
+// Returns:
+//
+//	The decoded text.
+func unescape(text string) string {
+	if !strings.ContainsAny(text, "%+") {
+		return text
+	}
+
+	builder := strings.Builder{}
+	builder.Grow(len(text))
+
+	for index := 0; index < len(text); index++ {
+		char := text[index]
+
+		switch {
+		case char == '+':
+			builder.WriteByte(' ')
+		case char == '%' && index+2 < len(text) && isHex(text[index+1]) && isHex(text[index+2]):
+			builder.WriteByte(unhex(text[index+1])<<4 | unhex(text[index+2]))
+			index += 2
+		default:
+			builder.WriteByte(char)
+		}
+	}
+
+	return builder.String()
+}
+
+// Description:
+//
+//	Checks whether a byte is a hexadecimal digit.
+func isHex(char byte) bool {
+	return ('0' <= char && char <= '9') || ('a' <= char && char <= 'f') || ('A' <= char && char <= 'F')
+}
+
+// Description:
+//
+//	Converts a hexadecimal digit to its value.
+func unhex(char byte) byte {
+	switch {
+	case '0' <= char && char <= '9':
+		return char - '0'
+	case 'a' <= char && char <= 'f':
+		return char - 'a' + 10
+	}
+
+	return char - 'A' + 10
+}
@@ -0,0 +1,385 @@
+package waf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+// Constant declarations.
+const (
+	// Rejects matching requests.
+	ActionBlock string = "block"
+
+	// Logs matching requests and passes them on.
+	ActionLog string = "log"
+
+	// Adds a tag to matching requests and passes them on.
+	ActionTag string = "tag"
+)
+
+// Constant declarations.
+const (
+	targetMethod  string = "method"
+	targetPath    string = "path"
+	targetQuery   string = "query"
+	targetHeader  string = "header"
+	targetHeaders string = "headers"
+	targetBody    string = "body"
+)
+
+// The default ruleset.
+var defaultRules = []config.ConfigWafRule{
+	{
+		Id: "sql-injection",
+		Conditions: []config.ConfigWafCondition{
+			{
+				Targets:  []string{targetQuery, targetBody},
+				Operator: "regex",
+				Value: `(?i)(\bunion\b\s+(all\s+)?select\b|'\s*(or|and)\s+'?[\w-]+'?\s*(=|like)\s*'?[\w-]+|;\s*(drop|truncate|alter)\s+(table|database)\b|` +
+					`\b(sleep|benchmark|pg_sleep)\s*\(\s*\d|\bwaitfor\s+delay\s+'|\binformation_schema\b|\bxp_cmdshell\b)`,
+			},
+		},
+	},
+	{
+		Id: "path-traversal",
+		Conditions: []config.ConfigWafCondition{
+			{
+				Targets:  []string{targetPath, targetQuery},
+				Operator: "regex",
+				Value:    `(?i)(\.\.[/\\]|%2e%2e|/etc/(passwd|shadow)\b|/proc/self/|\b(boot|win)\.ini\b)`,
+			},
+		},
+	},
+	{
+		Id: "scanner-user-agent",
+		Conditions: []config.ConfigWafCondition{
+			{
+				Targets:  []string{targetHeader + ":User-Agent"},
+				Operator: "regex",
+				Value:    `(?i)(sqlmap|nikto|nmap|masscan|zgrab|nuclei|acunetix|netsparker|wpscan|dirbuster|gobuster|havij|w3af|openvas|commix)`,
+			},
+		},
+	},
+}
+
+// Description:
+//
+//	A compiled rule.
+type rule struct {
+	id         string
+	action     string
+	tag        string
+	conditions []condition
+	hits       atomic.Uint64
+}
+
+// Description:
+//
+//	A compiled condition.
+//	Size conditions match, if a target is larger than the size, all other conditions use the match function.
+type condition struct {
+	targets []target
+	match   func(value string) bool
+	size    int64
+}
+
+// Description:
+//
+//	An inspected part of a request.
+type target struct {
+	kind string
+	name string
+}
+
+// Description:
+//
+//	Compiles the rules of a server.
+//
+// Parameters:
+//
+//	conf The firewall configuration.
+//
+// Returns:
+//
+//	The rules in the order they are applied, or an error, if a rule is invalid.
+func compileRules(conf config.ConfigReverseProxyServerWaf) ([]*rule, error) {
+	configured := []config.ConfigWafRule{}
+
+	if conf.DefaultRules {
+		action := conf.DefaultRulesAction
+
+		if action == "" {
+			action = ActionBlock
+		}
+
+		if action != ActionBlock && action != ActionLog {
+			return nil, fmt.Errorf("waf: invalid default rules action: %s", action)
+		}
+
+		disabled := map[string]bool{}
+
+		for _, id := range conf.DisabledRules {
+			disabled[id] = true
+		}
+
+		for _, defaultRule := range defaultRules {
+			if !disabled[defaultRule.Id] {
+				defaultRule.Action = action
+				configured = append(configured, defaultRule)
+			}
+		}
+	}
+
+	configured = append(configured, conf.Rules...)
+
+	rules := []*rule{}
+	ids := map[string]bool{}
+
+	for _, ruleConf := range configured {
+		compiled, err := compileRule(ruleConf)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ids[compiled.id] {
+			return nil, fmt.Errorf("waf: duplicate rule id: %s", compiled.id)
+		}
+
+		ids[compiled.id] = true
+		rules = append(rules, compiled)
+	}
+
+	return rules, nil
+}
+
+// Description:
+//
+//	Compiles a rule.
+//
+// Parameters:
+//
+//	conf The rule configuration.
+//
+// Returns:
+//
+//	The rule, or an error, if the rule is invalid.
+func compileRule(conf config.ConfigWafRule) (*rule, error) {
+	if conf.Id == "" {
+		return nil, fmt.Errorf("waf: rule without id")
+	}
+
+	if conf.Action != ActionBlock && conf.Action != ActionLog && conf.Action != ActionTag {
+		return nil, fmt.Errorf("waf: invalid action: %s: %s", conf.Id, conf.Action)
+	}
+
+	if len(conf.Conditions) == 0 {
+		return nil, fmt.Errorf("waf: rule without conditions: %s", conf.Id)
+	}
+
+	compiled := &rule{id: conf.Id, action: conf.Action, tag: conf.Tag}
+
+	if compiled.tag == "" {
+		compiled.tag = conf.Id
+	}
+
+	for _, conditionConf := range conf.Conditions {
+		condition, err := compileCondition(conditionConf)
+
+		if err != nil {
+			return nil, fmt.Errorf("waf: invalid condition: %s: %s", conf.Id, err)
+		}
+
+		compiled.conditions = append(compiled.conditions, condition)
+	}
+
+	return compiled, nil
+}
+
+// Description:
+//
+//	Compiles a condition.
+//
+// Parameters:
+//
+//	conf The condition configuration.
+//
+// Returns:
+//
+//	The condition, or an error, if the condition is invalid.
+func compileCondition(conf config.ConfigWafCondition) (condition, error) {
+	compiled := condition{size: -1}
+
+	if len(conf.Targets) == 0 {
+		return compiled, fmt.Errorf("no targets")
+	}
+
+	for _, text := range conf.Targets {
+		kind, name, _ := strings.Cut(text, ":")
+
+		switch {
+		case kind == targetMethod || kind == targetPath || kind == targetHeaders || kind == targetBody:
+			if name != "" {
+				return compiled, fmt.Errorf("invalid target: %s", text)
+			}
+		case kind == targetHeader && name != "":
+			name = strings.ToLower(name)
+		case kind == targetQuery:
+		default:
+			return compiled, fmt.Errorf("invalid target: %s", text)
+		}
+
+		compiled.targets = append(compiled.targets, target{kind: kind, name: name})
+	}
+
+	value := conf.Value
+
+	switch conf.Operator {
+	case "regex":
+		expression, err := regexp.Compile(value)
+
+		if err != nil {
+			return compiled, err
+		}
+
+		compiled.match = expression.MatchString
+	case "contains":
+		compiled.match = func(text string) bool { return strings.Contains(text, value) }
+	case "equals":
+		compiled.match = func(text string) bool { return text == value }
+	case "size":
+		size, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil || size < 0 {
+			return compiled, fmt.Errorf("invalid size: %s", value)
+		}
+
+		compiled.size = size
+	default:
+		return compiled, fmt.Errorf("invalid operator: %s", conf.Operator)
+	}
+
+	return compiled, nil
+}
+
+// Description:
+//
+//	Checks whether a rule matches a request, i.e. all of its conditions match.
+//
+// Parameters:
+//
+//	inspected The inspected request.
+//
+// Returns:
+//
+//	True, if the rule matches.
+func (rule *rule) matches(inspected *inspection) bool {
+	for _, condition := range rule.conditions {
+		if !condition.matches(inspected) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Description:
+//
+//	Checks whether a condition matches a request, i.e. any of its targets matches.
+//
+// Parameters:
+//
+//	inspected The inspected request.
+//
+// Returns:
+//
+//	True, if the condition matches.
+func (condition condition) matches(inspected *inspection) bool {
+	for _, target := range condition.targets {
+		if condition.size >= 0 && target.kind == targetBody {
+			if inspected.bodySize > condition.size {
+				return true
+			}
+
+			continue
+		}
+
+		for _, value := range inspected.values(target) {
+			if condition.size >= 0 {
+				if int64(len(value)) > condition.size {
+					return true
+				}
+
+				continue
+			}
+
+			if condition.match(value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Checks whether any rule inspects the request body.
+//
+// Parameters:
+//
+//	rules The rules.
+//
+// Returns:
+//
+//	True, if the body has to be read.
+func inspectsBody(rules []*rule) bool {
+	for _, rule := range rules {
+		for _, condition := range rule.conditions {
+			for _, target := range condition.targets {
+				if target.kind == targetBody {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Description:
+//
+//	Gets the amount of body bytes required to evaluate all body size conditions,
+//	i.e. one byte more than the largest size.
+//
+// Parameters:
+//
+//	rules The rules.
+//
+// Returns:
+//
+//	The amount of bytes, or 0, if no condition checks the body size.
+func bodySizeLimit(rules []*rule) int64 {
+	limit := int64(0)
+
+	for _, rule := range rules {
+		for _, condition := range rule.conditions {
+			if condition.size < 0 {
+				continue
+			}
+
+			for _, target := range condition.targets {
+				if target.kind == targetBody && condition.size+1 > limit {
+					limit = condition.size + 1
+				}
+			}
+		}
+	}
+
+	return limit
+}
@@ -0,0 +1,341 @@
+package waf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/revx-official/revx/pkg/config"
+	"github.com/revx-official/revx/pkg/logging"
+	"github.com/revx-official/revx/pkg/proxy"
+	"github.com/revx-official/revx/pkg/router"
+)
+
+// The waf subsystem logger.
+var log = logging.NewLogger("waf")
+
+// Constant declarations.
+const (
+	defaultStatus      int    = http.StatusForbidden
+	defaultMaxBodySize uint32 = 64 * 1024
+	defaultTagHeader   string = "X-Waf-Tags"
+)
+
+// Description:
+//
+//	The rule hit statistics of a server.
+type WafRuleHitsInfo struct {
+	Server string `json:"server"`
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Hits   uint64 `json:"hits"`
+}
+
+// The rules of all servers, used for the hit statistics.
+var registry = struct {
+	mutex   sync.Mutex
+	servers map[string][]*rule
+}{servers: map[string][]*rule{}}
+
+// Description:
+//
+//	A web application firewall of a server.
+type firewall struct {
+	server       string
+	rules        []*rule
+	status       int
+	maxBodySize  int64
+	sizeLimit    int64
+	tagHeader    string
+	inspectsBody bool
+}
+
+// Description:
+//
+//	The inspected parts of a request.
+type inspection struct {
+	request  *http.Request
+	query    []parameter
+	body     []string
+	bodySize int64
+}
+
+// Description:
+//
+//	Creates a middleware, which inspects requests by the rules of a server.
+//	Matching block rules reject the request, log rules are logged and tag rules add a request header for the upstreams.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The middleware, or an error, if a rule is invalid.
+func Middleware(conf config.ConfigReverseProxyServer) (router.RouterProxyMiddlewareFunc, error) {
+	if !conf.Waf.Enabled {
+		return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+			return handler
+		}, nil
+	}
+
+	firewall, err := newFirewall(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	registry.mutex.Lock()
+	registry.servers[conf.Name] = firewall.rules
+	registry.mutex.Unlock()
+
+	return func(handler router.RouterProxyHandlerFunc) router.RouterProxyHandlerFunc {
+		return func(request *http.Request, response http.ResponseWriter) {
+			request.Header.Del(firewall.tagHeader)
+
+			inspected, err := firewall.inspect(request)
+
+			if err != nil {
+				log.Debugf("waf: failed to read request body: %s: %s%s", firewall.server, err, proxy.RequestIdSuffix(request))
+				proxy.WriteError(response, request, http.StatusBadRequest, "Bad request.")
+
+				return
+			}
+
+			tags := []string{}
+
+			for _, rule := range firewall.rules {
+				if !rule.matches(inspected) {
+					continue
+				}
+
+				rule.hits.Add(1)
+
+				switch rule.action {
+				case ActionBlock:
+					log.Warnf("waf: request blocked: %s: %s: %s %q%s", firewall.server, rule.id, request.Method, request.URL.Path, proxy.RequestIdSuffix(request))
+					proxy.WriteError(response, request, firewall.status, "Request blocked.")
+
+					return
+				case ActionLog:
+					log.Warnf("waf: rule matched: %s: %s: %s %q%s", firewall.server, rule.id, request.Method, request.URL.Path, proxy.RequestIdSuffix(request))
+				case ActionTag:
+					log.Debugf("waf: request tagged: %s: %s: %s%s", firewall.server, rule.id, rule.tag, proxy.RequestIdSuffix(request))
+					tags = append(tags, rule.tag)
+				}
+			}
+
+			if len(tags) > 0 {
+				request.Header.Set(firewall.tagHeader, strings.Join(tags, ","))
+			}
+
+			handler(request, response)
+		}
+	}, nil
+}
+
+// Description:
+//
+//	Creates the firewall of a server.
+//
+// Parameters:
+//
+//	conf The server configuration.
+//
+// Returns:
+//
+//	The firewall, or an error, if the configuration is invalid.
+func newFirewall(conf config.ConfigReverseProxyServer) (*firewall, error) {
+	waf := conf.Waf
+
+	rules, err := compileRules(waf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	firewall := &firewall{
+		server:       conf.Name,
+		rules:        rules,
+		status:       waf.Status,
+		maxBodySize:  int64(waf.MaxBodySize),
+		sizeLimit:    bodySizeLimit(rules),
+		tagHeader:    waf.TagHeader,
+		inspectsBody: inspectsBody(rules),
+	}
+
+	if firewall.status == 0 {
+		firewall.status = defaultStatus
+	}
+
+	if firewall.status < 400 || firewall.status > 599 {
+		return nil, fmt.Errorf("waf: invalid status: %d", firewall.status)
+	}
+
+	if firewall.maxBodySize == 0 {
+		firewall.maxBodySize = int64(defaultMaxBodySize)
+	}
+
+	if firewall.tagHeader == "" {
+		firewall.tagHeader = defaultTagHeader
+	}
+
+	return firewall, nil
+}
+
+// Description:
+//
+//	Collects the inspected parts of a request.
+//	Query parameters and url encoded form bodies are decoded parameter by parameter, like upstreams decode them.
+//	The request body is only read, if a rule inspects it, and at most up to the maximum body size.
+//	Bodies without a content length are read up to the largest body size rule, so their size is known.
+//	The read bytes are put back in front of the remaining body, so the upstreams receive the whole body.
+//
+// Parameters:
+//
+//	request The request.
+//
+// Returns:
+//
+//	The inspection, or an error, if the request body cannot be read.
+func (firewall *firewall) inspect(request *http.Request) (*inspection, error) {
+	inspected := &inspection{request: request, query: parseParameters(request.URL.RawQuery)}
+
+	if !firewall.inspectsBody || request.Body == nil || request.Body == http.NoBody {
+		return inspected, nil
+	}
+
+	limit := firewall.maxBodySize
+
+	if request.ContentLength < 0 && firewall.sizeLimit > limit {
+		limit = firewall.sizeLimit
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(request.Body, limit))
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), request.Body), request.Body}
+
+	inspected.bodySize = request.ContentLength
+
+	if inspected.bodySize < 0 {
+		inspected.bodySize = int64(len(prefix))
+	}
+
+	if int64(len(prefix)) > firewall.maxBodySize {
+		prefix = prefix[:firewall.maxBodySize]
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+	if mediaType != "application/x-www-form-urlencoded" {
+		inspected.body = []string{string(prefix)}
+		return inspected, nil
+	}
+
+	for _, parameter := range parseParameters(string(prefix)) {
+		inspected.body = append(inspected.body, parameter.name+"="+parameter.value)
+	}
+
+	return inspected, nil
+}
+
+// Description:
+//
+//	Gets the values of an inspected part of the request.
+//
+// Parameters:
+//
+//	target The inspected part.
+//
+// Returns:
+//
+//	The values.
+func (inspected *inspection) values(target target) []string {
+	request := inspected.request
+
+	switch target.kind {
+	case targetMethod:
+		return []string{request.Method}
+	case targetPath:
+		return []string{request.URL.Path}
+	case targetQuery:
+		values := []string{}
+
+		for _, parameter := range inspected.query {
+			if target.name == "" {
+				values = append(values, parameter.name+"="+parameter.value)
+			} else if parameter.name == target.name {
+				values = append(values, parameter.value)
+			}
+		}
+
+		return values
+	case targetHeader:
+		if target.name == "host" {
+			return []string{request.Host}
+		}
+
+		return request.Header.Values(target.name)
+	case targetHeaders:
+		values := []string{}
+
+		for name, headerValues := range request.Header {
+			for _, value := range headerValues {
+				values = append(values, name+": "+value)
+			}
+		}
+
+		return values
+	case targetBody:
+		return inspected.body
+	}
+
+	return nil
+}
+
+// Description:
+//
+//	Gets the rule hit statistics of all servers.
+//
+// Returns:
+//
+//	The statistics, sorted by server and in rule order.
+func Hits() []WafRuleHitsInfo {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	servers := []string{}
+
+	for server := range registry.servers {
+		servers = append(servers, server)
+	}
+
+	sort.Strings(servers)
+
+	hits := []WafRuleHitsInfo{}
+
+	for _, server := range servers {
+		for _, rule := range registry.servers[server] {
+			hits = append(hits, WafRuleHitsInfo{
+				Server: server,
+				Rule:   rule.id,
+				Action: rule.action,
+				Hits:   rule.hits.Load(),
+			})
+		}
+	}
+
+	return hits
+}
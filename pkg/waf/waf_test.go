@@ -0,0 +1,287 @@
+package waf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/revx-official/revx/pkg/config"
+)
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		text    string
+		decoded string
+	}{
+		{text: "", decoded: ""},
+		{text: "plain", decoded: "plain"},
+		{text: "a+b", decoded: "a b"},
+		{text: "%27%20OR%201%3D1", decoded: "' OR 1=1"},
+		{text: "%2e%2E%2f", decoded: "../"},
+		{text: "%zz", decoded: "%zz"},
+		{text: "%", decoded: "%"},
+		{text: "%2", decoded: "%2"},
+		{text: "100%", decoded: "100%"},
+		{text: "%41%", decoded: "A%"},
+		{text: "%252e", decoded: "%2e"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if decoded := unescape(test.text); decoded != test.decoded {
+				t.Fatalf("expected %q, got %q", test.decoded, decoded)
+			}
+		})
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		parameters []parameter
+	}{
+		{name: "empty", text: "", parameters: []parameter{}},
+		{name: "pairs", text: "a=1&b=2", parameters: []parameter{{"a", "1"}, {"b", "2"}}},
+		{name: "decoded names and values", text: "q%5B%5D=%27+union&x+y=z", parameters: []parameter{{"q[]", "' union"}, {"x y", "z"}}},
+		{name: "without value", text: "flag&a=", parameters: []parameter{{"flag", ""}, {"a", ""}}},
+		{name: "empty pairs", text: "&&a=1&", parameters: []parameter{{"a", "1"}}},
+		{name: "repeated names", text: "a=1&a=2", parameters: []parameter{{"a", "1"}, {"a", "2"}}},
+		{name: "invalid escape keeps the pair", text: "a=%zz&b=%27", parameters: []parameter{{"a", "%zz"}, {"b", "'"}}},
+		{name: "semicolon keeps the pair", text: "a=1;b=2", parameters: []parameter{{"a", "1;b=2"}}},
+		{name: "equals in value", text: "a=b=c", parameters: []parameter{{"a", "b=c"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parameters := parseParameters(test.text)
+
+			if len(parameters) != len(test.parameters) {
+				t.Fatalf("expected %v, got %v", test.parameters, parameters)
+			}
+
+			for index, parameter := range parameters {
+				if parameter != test.parameters[index] {
+					t.Fatalf("expected %v, got %v", test.parameters, parameters)
+				}
+			}
+		})
+	}
+}
+
+// Description:
+//
+//	Creates a firewall for a server configuration.
+func testFirewall(t *testing.T, waf config.ConfigReverseProxyServerWaf) *firewall {
+	t.Helper()
+
+	waf.Enabled = true
+	firewall, err := newFirewall(config.ConfigReverseProxyServer{Name: "test", Waf: waf})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return firewall
+}
+
+// Description:
+//
+//	Gets the ids of the rules matching a request.
+func matchingRules(t *testing.T, firewall *firewall, request *http.Request) []string {
+	t.Helper()
+
+	inspected, err := firewall.inspect(request)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+
+	for _, rule := range firewall.rules {
+		if rule.matches(inspected) {
+			ids = append(ids, rule.id)
+		}
+	}
+
+	return ids
+}
+
+func TestDefaultRules(t *testing.T) {
+	firewall := testFirewall(t, config.ConfigReverseProxyServerWaf{DefaultRules: true})
+	form := "application/x-www-form-urlencoded"
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		userAgent   string
+		rule        string
+	}{
+		{name: "harmless request", target: "/api/users?name=o%27brien&sort=name"},
+		{name: "harmless form", method: http.MethodPost, target: "/login", contentType: form, body: "user=alice&password=s3cret"},
+		{name: "union select", target: "/items?id=1+UNION+SELECT+password+FROM+users", rule: "sql-injection"},
+		{name: "encoded union select", target: "/items?id=1%20union%20all%20select%20*", rule: "sql-injection"},
+		{name: "tautology", target: "/items?id=%27%20or%20%271%27%3D%271", rule: "sql-injection"},
+		{name: "stacked drop table", target: "/items?id=1;%20DROP%20TABLE%20users", rule: "sql-injection"},
+		{name: "time based", target: "/items?id=1+AND+sleep(5)", rule: "sql-injection"},
+		{name: "information schema", target: "/items?q=information_schema.tables", rule: "sql-injection"},
+		{name: "invalid escape does not hide the payload", target: "/items?x=%zz&id=1+union+select+1", rule: "sql-injection"},
+		{name: "semicolon does not hide the payload", target: "/items?x=1;id=1+union+select+1", rule: "sql-injection"},
+		{name: "union select in form", method: http.MethodPost, target: "/search", contentType: form, body: "q=1%20union%20select%201", rule: "sql-injection"},
+		{name: "union select in json", method: http.MethodPost, target: "/search", contentType: "application/json", body: `{"q":"1 union select 1"}`, rule: "sql-injection"},
+		{name: "traversal in query", target: "/download?file=..%2f..%2fetc%2fpasswd", rule: "path-traversal"},
+		{name: "double encoded traversal", target: "/download?file=%252e%252e%252fsecret", rule: "path-traversal"},
+		{name: "windows traversal", target: `/download?file=..\windows\win.ini`, rule: "path-traversal"},
+		{name: "proc self", target: "/download?file=/proc/self/environ", rule: "path-traversal"},
+		{name: "scanner user agent", target: "/", userAgent: "sqlmap/1.7", rule: "scanner-user-agent"},
+		{name: "browser user agent", target: "/", userAgent: "Mozilla/5.0 (X11; Linux x86_64)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := test.method
+
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			request := httptest.NewRequest(method, test.target, strings.NewReader(test.body))
+
+			if test.contentType != "" {
+				request.Header.Set("Content-Type", test.contentType)
+			}
+
+			if test.userAgent != "" {
+				request.Header.Set("User-Agent", test.userAgent)
+			}
+
+			ids := matchingRules(t, firewall, request)
+
+			if test.rule == "" {
+				if len(ids) > 0 {
+					t.Fatalf("expected no match, got %v", ids)
+				}
+
+				return
+			}
+
+			if len(ids) != 1 || ids[0] != test.rule {
+				t.Fatalf("expected %s, got %v", test.rule, ids)
+			}
+		})
+	}
+}
+
+func TestDisabledDefaultRules(t *testing.T) {
+	firewall := testFirewall(t, config.ConfigReverseProxyServerWaf{
+		DefaultRules:  true,
+		DisabledRules: []string{"sql-injection"},
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/items?id=1+union+select+1", nil)
+
+	if ids := matchingRules(t, firewall, request); len(ids) > 0 {
+		t.Fatalf("expected no match, got %v", ids)
+	}
+}
+
+func TestBodySizeRule(t *testing.T) {
+	firewall := testFirewall(t, config.ConfigReverseProxyServerWaf{
+		MaxBodySize: 4,
+		Rules: []config.ConfigWafRule{
+			{
+				Id:         "large-body",
+				Action:     ActionBlock,
+				Conditions: []config.ConfigWafCondition{{Targets: []string{targetBody}, Operator: "size", Value: "8"}},
+			},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		matches bool
+	}{
+		{name: "small body", body: "12345678"},
+		{name: "large body", body: "123456789", matches: true},
+		{name: "small chunked body", body: "12345678", chunked: true},
+		{name: "large chunked body", body: "123456789", chunked: true, matches: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(test.body))
+
+			if test.chunked {
+				request.ContentLength = -1
+			}
+
+			ids := matchingRules(t, firewall, request)
+
+			if matches := len(ids) > 0; matches != test.matches {
+				t.Fatalf("expected %t, got %v", test.matches, ids)
+			}
+
+			body, err := io.ReadAll(request.Body)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != test.body {
+				t.Fatalf("expected the whole body %q, got %q", test.body, body)
+			}
+		})
+	}
+}
+
+func TestCompileRulesInvalid(t *testing.T) {
+	condition := config.ConfigWafCondition{Targets: []string{targetPath}, Operator: "contains", Value: "x"}
+
+	tests := []struct {
+		name string
+		waf  config.ConfigReverseProxyServerWaf
+	}{
+		{name: "invalid default rules action", waf: config.ConfigReverseProxyServerWaf{DefaultRules: true, DefaultRulesAction: ActionTag}},
+		{name: "missing id", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{{Action: ActionLog, Conditions: []config.ConfigWafCondition{condition}}}}},
+		{name: "invalid action", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{{Id: "a", Action: "drop", Conditions: []config.ConfigWafCondition{condition}}}}},
+		{name: "without conditions", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{{Id: "a", Action: ActionLog}}}},
+		{name: "duplicate id", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{condition}},
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{condition}},
+		}}},
+		{name: "duplicate default rule id", waf: config.ConfigReverseProxyServerWaf{DefaultRules: true, Rules: []config.ConfigWafRule{
+			{Id: "sql-injection", Action: ActionLog, Conditions: []config.ConfigWafCondition{condition}},
+		}}},
+		{name: "invalid target", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{{Targets: []string{"cookie"}, Operator: "contains", Value: "x"}}},
+		}}},
+		{name: "header without name", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{{Targets: []string{targetHeader}, Operator: "contains", Value: "x"}}},
+		}}},
+		{name: "invalid regex", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{{Targets: []string{targetPath}, Operator: "regex", Value: "("}}},
+		}}},
+		{name: "invalid size", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{{Targets: []string{targetBody}, Operator: "size", Value: "-1"}}},
+		}}},
+		{name: "invalid operator", waf: config.ConfigReverseProxyServerWaf{Rules: []config.ConfigWafRule{
+			{Id: "a", Action: ActionLog, Conditions: []config.ConfigWafCondition{{Targets: []string{targetPath}, Operator: "like", Value: "x"}}},
+		}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := compileRules(test.waf); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}